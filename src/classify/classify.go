@@ -0,0 +1,185 @@
+// Package classify labels flights as military, government, state, medical
+// or civil from their ICAO address, registration, callsign, aircraft type
+// and operator.
+package classify
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"radar/icao"
+	"radar/model"
+)
+
+const (
+	Military = "military"
+	// Government covers VIP and head of state transport.
+	Government = "government"
+	// State covers non-military state aircraft: police, customs, border
+	// and coast guard.
+	State   = "state"
+	Medical = "medical"
+	Civil   = "civil"
+)
+
+// Classes in order of precedence: a flight with evidence for several gets
+// the first.
+var Classes = []string{Military, Government, State, Medical, Civil}
+
+// Result is a class together with the evidence it was chosen on.
+type Result struct {
+	Class   string   `json:"class"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// callsigns are prefixes that are followed by a flight number.
+var callsigns = map[string]string{
+	// Air forces and air transport commands.
+	"NATO": Military, "RCH": Military, "RRR": Military, "ASCOT": Military,
+	"CFC": Military, "GAF": Military, "GAM": Military, "IAM": Military,
+	"CTM": Military, "BAF": Military, "NAF": Military, "PLF": Military,
+	"ASY": Military, "KIWI": Military, "CNV": Military, "PAT": Military,
+	"SUI": Military, "HUAF": Military, "AME": Military, "SVF": Military,
+	"FNY": Military, "TUAF": Military, "HAF": Military, "CEF": Military,
+	"SAM": Government, "SPAR": Government, "EXEC": Government,
+	"POLICE": State, "BORDER": State, "CUSTOMS": State,
+	"HEMS": Medical, "MEDIC": Medical, "LIFEGUARD": Medical, "CHX": Medical,
+	"HELIMED": Medical, "SAMU": Medical, "AIRAMB": Medical,
+}
+
+var callsignNumber = regexp.MustCompile(`^([A-Z]+)[0-9]`)
+
+// registrations are formats of military serials, which carry no
+// nationality mark.
+var registrations = []struct {
+	pattern *regexp.Regexp
+	reason  string
+}{
+	{regexp.MustCompile(`^LX-N`), "NATO registration"},
+	{regexp.MustCompile(`^[0-9]{2}-[0-9]{3,5}$`), "US military serial"},
+	{regexp.MustCompile(`^[0-9]{2}\+[0-9]{2}$`), "German military serial"},
+	{regexp.MustCompile(`^MM[0-9]`), "Italian military serial"},
+	{regexp.MustCompile(`^Z[A-Z][0-9]{3}$`), "UK military serial"},
+}
+
+// types are ICAO type designators only flown by armed forces.
+var types = map[string]bool{
+	"E3TF": true, "E3CF": true, "E6": true, "E2": true, "E737": true,
+	"K35R": true, "K35E": true, "KC46": true,
+	"C17": true, "C5M": true, "C130": true, "C30J": true, "A400": true,
+	"C2": true, "C27J": true, "P8": true, "P3": true,
+	"R135": true, "U2": true, "B52": true, "B1": true, "B2": true,
+	"F16": true, "F15": true, "F18": true, "F35": true, "EUFI": true,
+	"RFAL": true, "TOR": true, "H47": true, "H60": true, "V22": true,
+	"Q9": true, "Q4": true,
+}
+
+// operators are ICAO airline designators of state operators.
+var operators = map[string]string{
+	"OAN": Military, // NATO
+}
+
+// keywords are matched against the whole words of operator names, so
+// "nato" does not match "Senator" and "hems" does not match "Chemservice".
+var keywords = []struct {
+	word  string
+	class string
+}{
+	{"air force", Military}, {"navy", Military}, {"army", Military},
+	{"military", Military}, {"marine corps", Military}, {"luftwaffe", Military},
+	{"nato", Military}, {"armée", Military},
+	{"government", Government}, {"ministry", Government}, {"presidential", Government},
+	{"police", State}, {"coast guard", State}, {"customs", State}, {"border", State},
+	{"ambulance", Medical}, {"rescue", Medical}, {"medical", Medical}, {"hems", Medical},
+}
+
+// Flight classifies a canonical flight.
+func Flight(f *model.Flight) Result {
+	evidence := make(map[string][]string)
+	add := func(class, reason string) {
+		evidence[class] = append(evidence[class], reason)
+	}
+
+	if addr, err := icao.ParseHex(f.Aircraft.Hex); err == nil {
+		if block, ok := icao.Military(addr); ok {
+			add(Military, fmt.Sprintf("address in %s military block", block.Country))
+		}
+	}
+	reg := strings.ToUpper(strings.TrimSpace(f.Aircraft.Registration))
+	for _, r := range registrations {
+		if r.pattern.MatchString(reg) {
+			add(Military, r.reason)
+		}
+	}
+	if m := callsignNumber.FindStringSubmatch(strings.ToUpper(f.Callsign)); m != nil {
+		if class, ok := callsigns[m[1]]; ok {
+			add(class, "callsign "+f.Callsign)
+		}
+	}
+	if types[strings.ToUpper(f.Aircraft.TypeCode)] {
+		add(Military, "aircraft type "+f.Aircraft.TypeCode)
+	}
+	for _, op := range []*model.Operator{f.Airline, f.Owner} {
+		if op == nil {
+			continue
+		}
+		if class, ok := operators[strings.ToUpper(op.Icao)]; ok {
+			add(class, "operator "+op.Icao)
+		}
+		name := words(op.Name)
+		for _, k := range keywords {
+			if name != "" && strings.Contains(name, " "+k.word+" ") {
+				add(k.class, "operator "+op.Name)
+				break
+			}
+		}
+	}
+
+	for _, class := range Classes {
+		if reasons, ok := evidence[class]; ok {
+			return Result{Class: class, Reasons: reasons}
+		}
+	}
+	return Result{Class: Civil}
+}
+
+// words lowercases name and separates its words by single spaces, with a
+// space at either end so phrases can be matched whole. It is empty when
+// name has no words.
+func words(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(fields) == 0 {
+		return ""
+	}
+	return " " + strings.Join(fields, " ") + " "
+}
+
+// ParseClasses reads a comma separated list of classes such as
+// "military,government".
+func ParseClasses(list string) ([]string, error) {
+	var classes []string
+	for _, c := range strings.Split(list, ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c == "" {
+			continue
+		}
+		if !Valid(c) {
+			return nil, fmt.Errorf("unknown class %q (want one of %s)", c, strings.Join(Classes, ", "))
+		}
+		classes = append(classes, c)
+	}
+	return classes, nil
+}
+
+func Valid(class string) bool {
+	for _, c := range Classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
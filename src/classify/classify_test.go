@@ -0,0 +1,36 @@
+package classify
+
+import (
+	"testing"
+
+	"radar/model"
+)
+
+func TestOperatorKeywords(t *testing.T) {
+	tests := []struct {
+		operator string
+		want     string
+	}{
+		// Keywords inside other words are not evidence.
+		{"Senator Aviation", Civil},
+		{"Donato Air", Civil},
+		{"Chemservice Air", Civil},
+		{"Armyworm Aerial Spraying", Civil},
+		// Whole words and phrases are.
+		{"NATO", Military},
+		{"NATO Airborne Early Warning", Military},
+		{"Royal Air Force", Military},
+		{"Armée de l'Air", Military},
+		{"ÖAMTC Christophorus HEMS", Medical},
+		{"Norsk Luftambulanse Air Ambulance", Medical},
+		{"U.S. Customs and Border Protection", State},
+		{"Ministry of Defence", Government},
+		{"", Civil},
+	}
+	for _, tt := range tests {
+		f := &model.Flight{Airline: &model.Operator{Name: tt.operator}}
+		if got := Flight(f).Class; got != tt.want {
+			t.Errorf("operator %q: class %s, want %s", tt.operator, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Package detect finds go-arounds and holding patterns in accumulated
+// trails and counts them per airport.
+package detect
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"radar/geo"
+	"radar/model"
+)
+
+const (
+	GoAround = "go_around"
+	Holding  = "holding"
+)
+
+// Enroute is the airport key of events not near the flight's airports.
+const Enroute = "enroute"
+
+type Event struct {
+	Kind         string `json:"kind"`
+	FlightID     string `json:"flight_id"`
+	Callsign     string `json:"callsign,omitempty"`
+	Registration string `json:"registration,omitempty"`
+	// Airport is the code of the airport the event happened at, or Enroute.
+	Airport  string    `json:"airport"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Duration int64     `json:"duration_seconds"`
+	// Position is the lowest point of a go-around and the center of a hold.
+	Position geo.Point `json:"position"`
+	Altitude int       `json:"altitude"`
+	// Turns counts the full turns flown in a hold.
+	Turns int `json:"turns,omitempty"`
+}
+
+func (e Event) String() string {
+	s := fmt.Sprintf("%s %s %s at %s %.4f,%.4f %dft %ds", e.Kind, e.Callsign, e.FlightID,
+		e.Airport, e.Position.Lat, e.Position.Lon, e.Altitude, e.Duration)
+	if e.Turns > 0 {
+		s += fmt.Sprintf(" %d turns", e.Turns)
+	}
+	return s
+}
+
+// Flight runs every detector over f's trail, oldest position first.
+func Flight(f *model.Flight) []Event {
+	events := append(goArounds(f), holds(f)...)
+	for i := range events {
+		e := &events[i]
+		e.FlightID = f.ID
+		e.Callsign = f.Callsign
+		e.Registration = f.Aircraft.Registration
+		e.Duration = int64(e.End.Sub(e.Start) / time.Second)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	return events
+}
+
+// Tracker runs the detectors over a flight's trail each time it grows and
+// reports each event once, however often the trail is seen again.
+type Tracker struct {
+	mu     sync.Mutex
+	seen   map[string]time.Time
+	counts map[string]map[string]int
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{seen: make(map[string]time.Time), counts: make(map[string]map[string]int)}
+}
+
+// Observe returns every event in f's trail and which of them are new.
+func (t *Tracker) Observe(f *model.Flight) (all, fresh []Event) {
+	all = Flight(f)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, e := range all {
+		key := fmt.Sprintf("%s/%s/%d", e.FlightID, e.Kind, e.Start.Unix())
+		if _, ok := t.seen[key]; ok {
+			continue
+		}
+		t.seen[key] = time.Now()
+		if t.counts[e.Airport] == nil {
+			t.counts[e.Airport] = make(map[string]int)
+		}
+		t.counts[e.Airport][e.Kind]++
+		fresh = append(fresh, e)
+	}
+	return all, fresh
+}
+
+// Expire forgets events seen more than maxAge ago. Their flights are long
+// gone, so they cannot be reported twice.
+func (t *Tracker) Expire(maxAge time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, at := range t.seen {
+		if time.Since(at) > maxAge {
+			delete(t.seen, key)
+		}
+	}
+}
+
+// Counts returns events per airport and kind since the tracker started.
+func (t *Tracker) Counts() map[string]map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	counts := make(map[string]map[string]int, len(t.counts))
+	for airport, kinds := range t.counts {
+		counts[airport] = make(map[string]int, len(kinds))
+		for kind, n := range kinds {
+			counts[airport][kind] = n
+		}
+	}
+	return counts
+}
+
+func (t *Tracker) WriteCounts(file string) error {
+	body, err := json.MarshalIndent(t.Counts(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Dir(file), 0777); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, body, 0644)
+}
+
+// nearest returns the closest of airports within radius meters of p.
+func nearest(p geo.Point, radius float64, airports ...*model.Airport) *model.Airport {
+	var best *model.Airport
+	for _, a := range airports {
+		if a == nil || (a.Lat == 0 && a.Lon == 0) {
+			continue
+		}
+		if d := geo.Distance(p, geo.Point{Lat: a.Lat, Lon: a.Lon}); d <= radius {
+			best, radius = a, d
+		}
+	}
+	return best
+}
+
+func airportKey(a *model.Airport) string {
+	if a == nil || a.Code() == "" {
+		return Enroute
+	}
+	return a.Code()
+}
+
+func point(p model.Position) geo.Point {
+	return geo.Point{Lat: p.Lat, Lon: p.Lon}
+}
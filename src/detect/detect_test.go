@@ -0,0 +1,110 @@
+package detect
+
+import (
+	"testing"
+	"time"
+
+	"radar/model"
+)
+
+var (
+	base        = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	destination = &model.Airport{Icao: "EDDF", Lat: 50.03, Lon: 8.57, Elevation: 364}
+)
+
+// profile is a trail one position a minute at the given altitudes, on the
+// ground at 0, offset north of the destination by lat degrees.
+func profile(lat float64, altitudes ...int) []model.Position {
+	trail := make([]model.Position, len(altitudes))
+	for i, alt := range altitudes {
+		trail[i] = model.Position{
+			Time:     base.Add(time.Duration(i) * time.Minute),
+			Lat:      destination.Lat + lat,
+			Lon:      destination.Lon,
+			Altitude: alt,
+			OnGround: alt == 0,
+		}
+	}
+	return trail
+}
+
+func TestGoArounds(t *testing.T) {
+	tests := []struct {
+		name  string
+		trail []model.Position
+		want  int
+	}{
+		{"landed", profile(0.02, 5000, 3000, 1500, 800, 0, 0), 0},
+		{"go-around", profile(0.02, 5000, 3000, 1500, 800, 1200, 2500, 4000), 1},
+		{"two attempts", profile(0.02, 3000, 800, 2500, 3000, 3000, 2000, 900, 2500, 3000, 1000, 0), 2},
+		{"climbs too little", profile(0.02, 3000, 800, 1000, 800, 0), 0},
+		{"trail ends low", profile(0.02, 5000, 3000, 800), 0},
+		{"far from the airport", profile(0.5, 5000, 3000, 1500, 800, 1200, 2500, 4000), 0},
+		{"too high above the airport", profile(0.02, 5000, 2500, 2000, 3500), 0},
+	}
+	for _, tt := range tests {
+		f := &model.Flight{ID: "f", Destination: destination, Trail: tt.trail}
+		events := goArounds(f)
+		if len(events) != tt.want {
+			t.Errorf("%s: %d go-arounds, want %d", tt.name, len(events), tt.want)
+			continue
+		}
+		for _, e := range events {
+			if e.Kind != GoAround || e.Airport != "EDDF" || e.Altitude > 1000 {
+				t.Errorf("%s: unexpected event %+v", tt.name, e)
+			}
+		}
+	}
+}
+
+// circling is a trail one position a minute at level flight, turning by
+// the given heading change each minute.
+func circling(minutes, perMinute int, altitude func(i int) int) []model.Position {
+	trail := make([]model.Position, minutes)
+	for i := range trail {
+		trail[i] = model.Position{
+			Time:     base.Add(time.Duration(i) * time.Minute),
+			Lat:      destination.Lat + 0.3,
+			Lon:      destination.Lon,
+			Altitude: altitude(i),
+			Track:    (i * perMinute) % 360,
+		}
+	}
+	return trail
+}
+
+func level(int) int { return 8000 }
+
+func TestHolds(t *testing.T) {
+	gap := circling(20, 45, level)
+	for i := 10; i < len(gap); i++ {
+		gap[i].Time = gap[i].Time.Add(10 * time.Minute)
+	}
+	tests := []struct {
+		name  string
+		trail []model.Position
+		want  int
+		turns int
+	}{
+		{"racetrack", circling(20, 45, level), 1, 2},
+		{"left turns", circling(20, -45, level), 1, 2},
+		{"straight", circling(20, 0, level), 0, 0},
+		{"one turn", circling(9, 45, level), 0, 0},
+		{"too slow for the window", circling(30, 20, level), 0, 0},
+		{"climbing", circling(20, 45, func(i int) int { return 4000 + 300*i }), 0, 0},
+		{"gap splits the turns", gap, 0, 0},
+	}
+	for _, tt := range tests {
+		f := &model.Flight{ID: "f", Destination: destination, Trail: tt.trail}
+		events := holds(f)
+		if len(events) != tt.want {
+			t.Errorf("%s: %d holds, want %d", tt.name, len(events), tt.want)
+			continue
+		}
+		for _, e := range events {
+			if e.Kind != Holding || e.Airport != "EDDF" || e.Turns < tt.turns || e.Altitude != 8000 {
+				t.Errorf("%s: unexpected event %+v", tt.name, e)
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package detect
+
+import "radar/model"
+
+const (
+	// goAroundRadius is how close to the destination, in meters, the
+	// descent has to come.
+	goAroundRadius = 15000
+	// goAroundHeight is how low above the destination, in feet.
+	goAroundHeight = 1500
+	// goAroundClimb is how far the aircraft must climb back up from its
+	// lowest point, in feet, without touching down first.
+	goAroundClimb = 700
+)
+
+// goArounds finds descents to low altitude near the destination (or the
+// diversion airport) that turn into a climb instead of a landing.
+func goArounds(f *model.Flight) []Event {
+	trail := f.Trail
+	var events []Event
+	for i := 0; i < len(trail); i++ {
+		a := low(trail[i], f)
+		if a == nil {
+			continue
+		}
+		lowest, j := i, i+1
+		for ; j < len(trail) && !trail[j].OnGround; j++ {
+			if trail[j].Altitude < trail[lowest].Altitude {
+				lowest = j
+			} else if trail[j].Altitude-trail[lowest].Altitude >= goAroundClimb {
+				break
+			}
+		}
+		if j == len(trail) || trail[j].OnGround {
+			// Landed, or the trail ends before we know.
+			i = j
+			continue
+		}
+		events = append(events, Event{
+			Kind:     GoAround,
+			Airport:  airportKey(a),
+			Start:    trail[lowest].Time,
+			End:      trail[j].Time,
+			Position: point(trail[lowest]),
+			Altitude: trail[lowest].Altitude,
+		})
+		// Skip the rest of the climb out before looking for the next try.
+		for j < len(trail) && low(trail[j], f) != nil {
+			j++
+		}
+		i = j
+	}
+	return events
+}
+
+// low returns the airport p is low and close to, if any.
+func low(p model.Position, f *model.Flight) *model.Airport {
+	if p.OnGround {
+		return nil
+	}
+	a := nearest(point(p), goAroundRadius, f.Destination, f.Diverted)
+	if a == nil || p.Altitude-a.Elevation > goAroundHeight {
+		return nil
+	}
+	return a
+}
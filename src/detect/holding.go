@@ -0,0 +1,104 @@
+package detect
+
+import (
+	"math"
+	"time"
+
+	"radar/geo"
+	"radar/model"
+)
+
+const (
+	// holdTurns full turns in the same direction within holdWindow make a
+	// hold. A standard racetrack takes about four minutes per turn.
+	holdTurns  = 2
+	holdWindow = 20 * time.Minute
+	// holdBand is how far, in feet, the altitude may wander during a hold.
+	holdBand = 500
+	// holdMaxGap splits the trail where positions are missing for longer.
+	holdMaxGap = 5 * time.Minute
+	// holdAirportRadius attributes holds within this many meters of one of
+	// the flight's airports to that airport.
+	holdAirportRadius = 80000
+)
+
+// holds finds stretches of level flight that turn through holdTurns full
+// circles within holdWindow.
+func holds(f *model.Flight) []Event {
+	trail := f.Trail
+	n := len(trail)
+	if n < 2 {
+		return nil
+	}
+
+	// turned[i] is the signed heading change from the first position to i.
+	turned := make([]float64, n)
+	for i := 1; i < n; i++ {
+		turned[i] = turned[i-1] + turn(trail[i-1].Track, trail[i].Track)
+	}
+
+	holding := make([]bool, n)
+	level, left := 0, 0
+	for i := 1; i < n; i++ {
+		p := trail[i]
+		if p.OnGround || trail[level].OnGround || p.Time.Sub(trail[i-1].Time) > holdMaxGap ||
+			abs(p.Altitude-trail[level].Altitude) > holdBand {
+			level, left = i, i
+			continue
+		}
+		for left < i && p.Time.Sub(trail[left].Time) > holdWindow {
+			left++
+		}
+		if math.Abs(turned[i]-turned[left]) >= 360*holdTurns {
+			for j := left; j <= i; j++ {
+				holding[j] = true
+			}
+		}
+	}
+
+	var events []Event
+	for i := 0; i < n; i++ {
+		if !holding[i] {
+			continue
+		}
+		j := i
+		for j+1 < n && holding[j+1] {
+			j++
+		}
+		events = append(events, holdEvent(f, i, j, turned))
+		i = j
+	}
+	return events
+}
+
+func holdEvent(f *model.Flight, first, last int, turned []float64) Event {
+	var lat, lon, alt float64
+	for _, p := range f.Trail[first : last+1] {
+		lat += p.Lat
+		lon += p.Lon
+		alt += float64(p.Altitude)
+	}
+	count := float64(last - first + 1)
+	center := geo.Point{Lat: lat / count, Lon: lon / count}
+	return Event{
+		Kind:     Holding,
+		Airport:  airportKey(nearest(center, holdAirportRadius, f.Origin, f.Destination, f.Diverted)),
+		Start:    f.Trail[first].Time,
+		End:      f.Trail[last].Time,
+		Position: center,
+		Altitude: int(math.Round(alt / count)),
+		Turns:    int(math.Abs(turned[last]-turned[first]) / 360),
+	}
+}
+
+// turn is the signed heading change from a to b, taking the short way.
+func turn(a, b int) float64 {
+	return math.Mod(float64(b-a)+540, 360) - 180
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
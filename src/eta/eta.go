@@ -0,0 +1,114 @@
+// Package eta estimates arrival times from a flight's position, its ground
+// speed trend and the great-circle distance left to its destination, for
+// the many flights FR24 reports no ETA for. Tracker keeps the estimates so
+// they can be compared with the actual arrival.
+package eta
+
+import (
+	"time"
+
+	"radar/geo"
+	"radar/model"
+	"radar/units"
+)
+
+const (
+	// descentGradient is the usual 3 nautical miles of track per 1000 ft
+	// of descent, in meters per foot.
+	descentGradient = 3 * units.MetersPerNauticalMile / 1000
+	// approachSpeed is a typical ground speed over the threshold, in knots.
+	// The descent is flown at the mean of it and the cruise speed.
+	approachSpeed = 140
+	// trendWindow is how far back the ground speed trend looks.
+	trendWindow = 10 * time.Minute
+	// minSpeed is the slowest ground speed, in knots, an estimate is made
+	// at; below it the aircraft is taxiing or the report is bad.
+	minSpeed = 50
+)
+
+type Estimate struct {
+	// Made is the time of the position the estimate starts from.
+	Made    time.Time `json:"made"`
+	Arrival time.Time `json:"arrival"`
+	// Destination is the airport code the estimate is for.
+	Destination string  `json:"destination"`
+	RemainingM  float64 `json:"remaining_m"`
+	// GroundSpeed is the trend speed at Made, in knots.
+	GroundSpeed int `json:"ground_speed"`
+	// FR24 is FR24's own ETA at the time, when it had one.
+	FR24 *time.Time `json:"fr24,omitempty"`
+}
+
+// Flight estimates when f reaches dest, or f.Destination when dest is nil.
+// It needs an airborne position and a destination with coordinates. f's
+// trail, oldest first, gives the ground speed trend.
+func Flight(f *model.Flight, dest *model.Airport) (*Estimate, bool) {
+	if dest == nil {
+		dest = f.Destination
+	}
+	p := f.Position
+	if p == nil || p.OnGround || dest == nil || (dest.Lat == 0 && dest.Lon == 0) {
+		return nil, false
+	}
+	speed := trend(f.Trail, *p)
+	if speed < minSpeed {
+		return nil, false
+	}
+
+	remaining := geo.Distance(geo.Point{Lat: p.Lat, Lon: p.Lon}, geo.Point{Lat: dest.Lat, Lon: dest.Lon})
+	// The descent starts far enough out to lose the height above the
+	// airport at descentGradient, or has already started.
+	descent := float64(p.Altitude-dest.Elevation) * descentGradient
+	if descent < 0 {
+		descent = 0
+	}
+	if descent > remaining {
+		descent = remaining
+	}
+	cruiseMps := units.KnotsToMps(speed)
+	descentMps := cruiseMps
+	if speed > approachSpeed {
+		descentMps = units.KnotsToMps((speed + approachSpeed) / 2)
+	}
+	seconds := (remaining-descent)/cruiseMps + descent/descentMps
+
+	e := &Estimate{
+		Made:        p.Time,
+		Arrival:     p.Time.Add(time.Duration(seconds * float64(time.Second))).Truncate(time.Second),
+		Destination: dest.Code(),
+		RemainingM:  units.Round(remaining, 0),
+		GroundSpeed: int(speed + 0.5),
+		FR24:        f.Times.Eta,
+	}
+	return e, true
+}
+
+// trend fits a line to the airborne ground speeds within trendWindow of p
+// and returns its value at p, which smooths out single bad reports while
+// following a steady speed change. With fewer than three points it is p's
+// own ground speed.
+func trend(trail []model.Position, p model.Position) float64 {
+	var n, sx, sy, sxx, sxy float64
+	for _, q := range trail {
+		age := p.Time.Sub(q.Time)
+		if q.OnGround || q.GroundSpeed <= 0 || age < 0 || age > trendWindow {
+			continue
+		}
+		x, y := -age.Seconds(), float64(q.GroundSpeed)
+		n++
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+	}
+	if n < 3 {
+		return float64(p.GroundSpeed)
+	}
+	d := n*sxx - sx*sx
+	if d == 0 {
+		return sy / n
+	}
+	slope := (n*sxy - sx*sy) / d
+	// The fitted value at x = 0, which is p's time.
+	return (sy - slope*sx) / n
+}
@@ -0,0 +1,119 @@
+package eta
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"radar/model"
+)
+
+var (
+	start       = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	destination = &model.Airport{Icao: "EDDM", Iata: "MUC", Lat: 0, Lon: 1, Elevation: 1000}
+)
+
+// at is a position on the equator, which lies one degree (111195 m) west of
+// destination at lon 0.
+func at(minute float64, lon float64, altitude, speed int) model.Position {
+	return model.Position{
+		Time:        start.Add(time.Duration(minute * float64(time.Minute))),
+		Lon:         lon,
+		Altitude:    altitude,
+		GroundSpeed: speed,
+		OnGround:    altitude == 0,
+	}
+}
+
+func TestFlight(t *testing.T) {
+	nowhere := &model.Airport{Icao: "ZZZZ"}
+	tests := []struct {
+		name     string
+		position model.Position
+		trail    []model.Position
+		dest     *model.Airport
+		want     int // seconds to arrival, -1 for no estimate
+	}{
+		{"on the ground", at(0, 0, 0, 20), nil, nil, -1},
+		{"too slow", at(0, 0, 3000, 40), nil, nil, -1},
+		{"destination without coordinates", at(0, 0, 3000, 300), nil, nowhere, -1},
+		// 111195 m at 360 kt (185.2 m/s), already at the airport's elevation.
+		{"level", at(0, 0, 1000, 360), nil, nil, 600},
+		// 10000 ft above the airport leaves 55560 m of descent at the mean
+		// of 360 and 140 kt (128.6 m/s).
+		{"descent ahead", at(0, 0, 11000, 360), nil, nil, 732},
+		// The descent is longer than the distance left: all of it at 250 kt.
+		{"descending", at(0, 0, 41000, 360), nil, nil, 864},
+		// A steady slowdown from 400 kt is followed to 360 kt at p, despite
+		// the last report saying 380.
+		{"speed trend", at(10, 0, 1000, 380), []model.Position{
+			at(0, -0.5, 1000, 400), at(5, -0.25, 1000, 380), at(8, -0.1, 1000, 368), at(10, 0, 1000, 360),
+		}, nil, 600},
+	}
+	for _, tt := range tests {
+		f := &model.Flight{ID: "f", Destination: destination, Position: &tt.position, Trail: tt.trail}
+		e, ok := Flight(f, tt.dest)
+		got := -1
+		if ok {
+			got = int(e.Arrival.Sub(e.Made) / time.Second)
+		}
+		if got != tt.want {
+			t.Errorf("%s: arrival in %ds, want %ds", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestArrive(t *testing.T) {
+	fr24 := start.Add(15 * time.Minute)
+	tests := []struct {
+		name        string
+		dest        *model.Airport // destination when not nil
+		observed    []float64      // minutes of the feed positions
+		actual      float64
+		airport     *model.Airport
+		predictions []int64 // error of every prediction in seconds
+		diverted    bool
+	}{
+		{"no estimates", nil, nil, 10, destination, nil, false},
+		// Estimates arrive 10 minutes after they are made.
+		{"one per sample interval", nil, []float64{0, 2, 5, 6, 11}, 12, destination, []int64{-120, 180, 540}, false},
+		{"estimates after the arrival", nil, []float64{0, 5, 10}, 7, destination, []int64{180, 480}, false},
+		{"estimated for the IATA code", &model.Airport{Iata: "MUC", Lat: 0, Lon: 1, Elevation: 1000}, []float64{0}, 10, destination, []int64{0}, false},
+		{"diverted", nil, []float64{0}, 10, &model.Airport{Icao: "EDDN"}, []int64{0}, true},
+		{"unknown airport", nil, []float64{0}, 10, nil, []int64{0}, false},
+	}
+	for _, tt := range tests {
+		tracker := NewTracker()
+		for _, minute := range tt.observed {
+			// 600 s to destination from every position.
+			p := at(minute, 0, 1000, 360)
+			f := &model.Flight{ID: "f", Callsign: "DLH1", Destination: destination, Position: &p}
+			f.Times.Eta = &fr24
+			tracker.Observe(f, tt.dest)
+		}
+		outcome := tracker.Arrive("f", start.Add(time.Duration(tt.actual*float64(time.Minute))), tt.airport)
+		if tt.predictions == nil {
+			if outcome != nil {
+				t.Errorf("%s: outcome %+v, want none", tt.name, outcome)
+			}
+			continue
+		}
+		if outcome == nil {
+			t.Errorf("%s: no outcome", tt.name)
+			continue
+		}
+		var errors []int64
+		for _, p := range outcome.Predictions {
+			errors = append(errors, p.ErrorSeconds)
+		}
+		if !slices.Equal(errors, tt.predictions) || outcome.Diverted != tt.diverted || outcome.Callsign != "DLH1" {
+			t.Errorf("%s: errors %v diverted %v, want %v %v", tt.name, errors, outcome.Diverted, tt.predictions, tt.diverted)
+		}
+		if p := outcome.Predictions[0]; p.FR24ErrorSeconds == nil || *p.FR24ErrorSeconds != int64(fr24.Sub(outcome.Actual)/time.Second) {
+			t.Errorf("%s: FR24 error %v", tt.name, p.FR24ErrorSeconds)
+		}
+		if again := tracker.Arrive("f", outcome.Actual, tt.airport); again != nil {
+			t.Errorf("%s: arrived twice", tt.name)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package eta
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"radar/model"
+)
+
+// sampleEvery is how often a flight's estimate is kept for evaluation.
+// Estimates in between are still returned, just not stored.
+const sampleEvery = 5 * time.Minute
+
+// Prediction is an estimate checked against the actual arrival. Errors are
+// estimate minus actual, so a positive error was late.
+type Prediction struct {
+	Estimate
+	ErrorSeconds     int64  `json:"error_s"`
+	FR24ErrorSeconds *int64 `json:"fr24_error_s,omitempty"`
+	// LeadSeconds is how long before the arrival the estimate was made.
+	LeadSeconds int64 `json:"lead_s"`
+}
+
+// Outcome is written once per arrival of a flight that had estimates.
+type Outcome struct {
+	FlightID     string    `json:"flight_id"`
+	Callsign     string    `json:"callsign,omitempty"`
+	Registration string    `json:"registration,omitempty"`
+	TypeCode     string    `json:"type_code,omitempty"`
+	Destination  string    `json:"destination"`
+	Airport      string    `json:"airport,omitempty"`
+	Actual       time.Time `json:"actual"`
+	// Diverted is set when the flight landed somewhere other than the
+	// airport its estimates were for; their errors mean little then.
+	Diverted    bool         `json:"diverted,omitempty"`
+	Predictions []Prediction `json:"predictions"`
+}
+
+func (o Outcome) String() string {
+	last := o.Predictions[len(o.Predictions)-1]
+	return fmt.Sprintf("%s %s at %s %s, %d estimates, last %+ds", o.Callsign, o.FlightID, o.Destination,
+		o.Actual.Format(time.RFC3339), len(o.Predictions), last.ErrorSeconds)
+}
+
+// Tracker keeps the estimates of every flight until it arrives. It is safe
+// for concurrent use.
+type Tracker struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+type flight struct {
+	callsign     string
+	registration string
+	typeCode     string
+	// recent are the feed positions within trendWindow, oldest first.
+	recent    []model.Position
+	estimates []Estimate
+	seen      time.Time
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{flights: make(map[string]*flight)}
+}
+
+// Observe estimates f's arrival at dest (f.Destination when nil) and keeps
+// one estimate per sampleEvery. f can be a feed snapshot without a trail;
+// the tracker remembers its recent positions for the speed trend.
+func (t *Tracker) Observe(f *model.Flight, dest *model.Airport) (*Estimate, bool) {
+	if f.Position == nil {
+		return nil, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fl, ok := t.flights[f.ID]
+	if !ok {
+		fl = &flight{}
+		t.flights[f.ID] = fl
+	}
+	fl.callsign, fl.registration, fl.typeCode = f.Callsign, f.Aircraft.Registration, f.Aircraft.TypeCode
+	fl.seen = time.Now()
+	if n := len(fl.recent); n == 0 || f.Position.Time.After(fl.recent[n-1].Time) {
+		fl.recent = append(fl.recent, *f.Position)
+	}
+	for len(fl.recent) > 0 && f.Position.Time.Sub(fl.recent[0].Time) > trendWindow {
+		fl.recent = fl.recent[1:]
+	}
+
+	withTrail := *f
+	if len(withTrail.Trail) == 0 {
+		withTrail.Trail = fl.recent
+	}
+	e, ok := Flight(&withTrail, dest)
+	if !ok {
+		return nil, false
+	}
+	n := len(fl.estimates)
+	if n > 0 && fl.estimates[n-1].Destination != e.Destination {
+		// A new destination makes the earlier estimates meaningless.
+		fl.estimates = nil
+		n = 0
+	}
+	if n == 0 || e.Made.Sub(fl.estimates[n-1].Made) >= sampleEvery {
+		fl.estimates = append(fl.estimates, *e)
+	}
+	return e, true
+}
+
+// Arrive closes a flight's estimates with its actual arrival at airport,
+// which may be nil when it is unknown. It returns nil when the flight had no
+// estimates made before actual.
+func (t *Tracker) Arrive(id string, actual time.Time, airport *model.Airport) *Outcome {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fl, ok := t.flights[id]
+	if !ok {
+		return nil
+	}
+	delete(t.flights, id)
+
+	var predictions []Prediction
+	for _, e := range fl.estimates {
+		if !e.Made.Before(actual) {
+			continue
+		}
+		p := Prediction{
+			Estimate:     e,
+			ErrorSeconds: int64(e.Arrival.Sub(actual) / time.Second),
+			LeadSeconds:  int64(actual.Sub(e.Made) / time.Second),
+		}
+		if e.FR24 != nil {
+			fr24 := int64(e.FR24.Sub(actual) / time.Second)
+			p.FR24ErrorSeconds = &fr24
+		}
+		predictions = append(predictions, p)
+	}
+	if len(predictions) == 0 {
+		return nil
+	}
+	o := &Outcome{
+		FlightID:     id,
+		Callsign:     fl.callsign,
+		Registration: fl.registration,
+		TypeCode:     fl.typeCode,
+		Destination:  predictions[0].Destination,
+		Actual:       actual,
+		Predictions:  predictions,
+	}
+	if airport != nil {
+		o.Airport = airport.Code()
+		o.Diverted = airport.Code() != o.Destination && airport.Iata != o.Destination
+	}
+	return o
+}
+
+// Expire forgets flights not seen for maxAge, which never reported an
+// arrival.
+func (t *Tracker) Expire(maxAge time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, fl := range t.flights {
+		if time.Since(fl.seen) > maxAge {
+			delete(t.flights, id)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"radar/export"
+	"radar/model"
+	"radar/track"
+	"radar/units"
+)
+
+// runExport is the export subcommand: radar export -format geojson|kml|kmz
+// [flags].
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "geojson", "output format: geojson, kml or kmz")
+	dataDir := fs.String("data", "Data", "directory the sweep stored records in")
+	out := fs.String("out", "-", "output file, - for stdout; with -split a directory")
+	split := fs.Bool("split", false, "write one file per flight instead of a single collection")
+	positions := fs.Bool("positions", true, "export each flight's latest position")
+	trails := fs.Bool("trails", true, "export each flight's trail")
+	since := fs.Duration("since", 0, "only flights updated within this long, e.g. 2h (0 exports all)")
+	simplify := fs.Float64("simplify", 0, "simplify trails to within this many meters across track (0 keeps every point)")
+	simplifyAlt := fs.Float64("simplify-alt", 30, "altitude tolerance in meters used with -simplify")
+	resample := fs.Duration("resample", 0, "resample trails to one point per interval, e.g. 30s (0 disables)")
+	opts := export.Options{Units: units.Aviation}
+	fs.Var(&opts.Units, "units", "units of altitude and speed properties: aviation or metric")
+	fs.Parse(args)
+
+	var write func(w io.Writer, flights []model.Flight, opts export.Options) error
+	var ext string
+	switch *format {
+	case "geojson":
+		write, ext = export.WriteGeoJSON, ".geojson"
+	case "kml":
+		write, ext = export.WriteKML, ".kml"
+	case "kmz":
+		write, ext = export.WriteKMZ, ".kmz"
+	default:
+		return fmt.Errorf("unknown export format %q", *format)
+	}
+
+	if *split && *out == "-" {
+		return fmt.Errorf("-split needs -out to name a directory")
+	}
+	opts.Positions, opts.Trails, opts.Resample = *positions, *trails, *resample
+	if *simplify > 0 {
+		opts.Simplify = track.Tolerance{Horizontal: *simplify, Vertical: *simplifyAlt}
+	}
+	var from time.Time
+	if *since > 0 {
+		from = time.Now().Add(-*since)
+	}
+	flights, err := export.Load(*dataDir, from)
+	if err != nil {
+		return err
+	}
+
+	if !*split {
+		if *out == "-" {
+			return write(os.Stdout, flights, opts)
+		}
+		if err := writeExport(*out, flights, opts, write); err != nil {
+			return err
+		}
+		fmt.Println("[INF] Exported", len(flights), "flights to", *out)
+		return nil
+	}
+	for _, flight := range flights {
+		file := filepath.Join(*out, flight.ID+ext)
+		if err := writeExport(file, []model.Flight{flight}, opts, write); err != nil {
+			return err
+		}
+	}
+	fmt.Println("[INF] Exported", len(flights), "flights to", *out)
+	return nil
+}
+
+func writeExport(file string, flights []model.Flight, opts export.Options, write func(io.Writer, []model.Flight, export.Options) error) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := write(f, flights, opts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
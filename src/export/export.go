@@ -0,0 +1,141 @@
+// Package export writes stored flights in formats other tools read: each
+// flight's latest position and its trail, with the flight's details as
+// properties.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"radar/flightRadar"
+	"radar/model"
+	"radar/phase"
+	"radar/track"
+	"radar/units"
+)
+
+// Options choose what is exported and how.
+type Options struct {
+	// Positions exports each flight's latest position, Trails its trail.
+	Positions bool
+	Trails    bool
+	// Units is the system altitudes and speeds are given in. Coordinates
+	// always carry the altitude in meters.
+	Units units.System
+	// Simplify and Resample reduce exported trails, see package track.
+	Simplify track.Tolerance
+	Resample time.Duration
+}
+
+// Load reads every record stored under dir, with the full trail from its
+// trail file, oldest updated first. Files that are not records, such as the
+// reference cache and photo sidecars, are skipped. Only flights updated
+// since then are kept unless it is zero.
+func Load(dir string, since time.Time) ([]model.Flight, error) {
+	var flights []model.Flight
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(file) != ".json" || strings.HasSuffix(file, ".tmp") {
+			return nil
+		}
+		flight, ok, err := loadRecord(file)
+		if err != nil {
+			return err
+		}
+		if ok && !flight.Updated.Before(since) {
+			flights = append(flights, flight)
+		}
+		return nil
+	})
+	sort.SliceStable(flights, func(i, j int) bool { return flights[i].Updated.Before(flights[j].Updated) })
+	return flights, err
+}
+
+// loadRecord reads a stored record with the trail from its trail file, or
+// the record's own trail when the file has no points. ok is false when file
+// does not hold a record.
+func loadRecord(file string) (flight model.Flight, ok bool, err error) {
+	body, err := ioutil.ReadFile(file)
+	if err != nil {
+		return flight, false, err
+	}
+	var details flightRadar.FlightDetails
+	if json.Unmarshal(body, &details) != nil || details.Identification.ID == "" {
+		return flight, false, nil
+	}
+
+	// Stored records may hold a resampled or simplified trail; the trail
+	// file has every point as it was seen, so the two are not mixed.
+	stored, err := flightRadar.ReadTrail(flightRadar.TrailFile(file))
+	if err != nil {
+		// The sweep may be appending to the file; the points before the
+		// line that could not be read are still good.
+		fmt.Println("[Err] Reading the trail of", details.Identification.ID, err)
+	}
+	if len(stored) > 0 {
+		// Newest first, as FR24 orders trails.
+		trail := make([]flightRadar.TrailPoint, len(stored))
+		for i, p := range stored {
+			trail[len(stored)-1-i] = p
+		}
+		details.Trail = trail
+	}
+	// Trail files carry no phases and the record's only cover the trail it
+	// was built from, so the whole trail is labelled again.
+	flight = details.Canonical()
+	phase.Flight(&flight)
+	if n := len(flight.Trail); n > 0 {
+		flight.Position.Phase = flight.Trail[n-1].Phase
+	}
+	return flight, true, nil
+}
+
+// reduce applies the trail options to a trail that is oldest first.
+func (o Options) reduce(trail []model.Position) []model.Position {
+	trail = track.Resample(trail, o.Resample)
+	if !o.Simplify.IsZero() {
+		trail = track.Simplify(trail, o.Simplify)
+	}
+	return trail
+}
+
+// properties are the flight details every exported feature carries.
+func properties(f *model.Flight) map[string]interface{} {
+	props := map[string]interface{}{
+		"flight_id": f.ID,
+	}
+	set := func(key, value string) {
+		if value != "" {
+			props[key] = value
+		}
+	}
+	set("callsign", f.Callsign)
+	set("number", f.Number)
+	set("registration", f.Aircraft.Registration)
+	set("hex", f.Aircraft.Hex)
+	set("model", f.Aircraft.TypeCode)
+	set("model_name", f.Aircraft.TypeName)
+	set("class", f.Class)
+	if f.Position != nil {
+		set("phase", f.Position.Phase)
+	}
+	if f.Airline != nil {
+		set("airline", f.Airline.Name)
+		set("airline_icao", f.Airline.Icao)
+	}
+	if f.Origin != nil {
+		set("origin", f.Origin.Code())
+	}
+	if f.Destination != nil {
+		set("destination", f.Destination.Code())
+	}
+	return props
+}
@@ -0,0 +1,68 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// record is a stored record whose own trail is two points at 100 and 200.
+const record = `{"identification":{"id":"%s"},"trail":[` +
+	`{"lat":1,"lng":1,"alt":1000,"spd":200,"ts":200,"hd":0},` +
+	`{"lat":1,"lng":1,"alt":0,"spd":0,"ts":100,"hd":0}]}`
+
+const trailLine = `{"lat":2,"lng":2,"alt":3000,"spd":250,"ts":%d,"hd":90}` + "\n"
+
+func TestLoadTrails(t *testing.T) {
+	tests := []struct {
+		id    string
+		trail string // "" writes no trail file
+		want  []int64
+	}{
+		{"complete", line(300) + line(400) + line(500), []int64{300, 400, 500}},
+		{"truncated", line(300) + line(400) + `{"lat":2,"lng":2,"al`, []int64{300, 400}},
+		{"unreadable", "garbage\n" + line(300), []int64{100, 200}},
+		{"missing", "", []int64{100, 200}},
+		{"empty", "\n", []int64{100, 200}},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		write(t, filepath.Join(dir, tt.id+".json"), fmt.Sprintf(record, tt.id))
+		if tt.trail != "" {
+			write(t, filepath.Join(dir, tt.id+".trail.jsonl"), tt.trail)
+		}
+	}
+	write(t, filepath.Join(dir, "airports.json"), `{"EDDF":{"name":"Frankfurt"}}`)
+
+	flights, err := Load(dir, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := make(map[string][]int64)
+	for _, f := range flights {
+		for _, p := range f.Trail {
+			byID[f.ID] = append(byID[f.ID], p.Time.Unix())
+		}
+	}
+	if len(flights) != len(tests) {
+		t.Errorf("loaded %d flights, want %d", len(flights), len(tests))
+	}
+	for _, tt := range tests {
+		if got := byID[tt.id]; !slices.Equal(got, tt.want) {
+			t.Errorf("%s: trail %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func line(ts int) string {
+	return fmt.Sprintf(trailLine, ts)
+}
+
+func write(t *testing.T, file, body string) {
+	if err := os.WriteFile(file, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,125 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"radar/model"
+	"radar/units"
+)
+
+// FeatureCollection is a GeoJSON (RFC 7946) document.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+type Feature struct {
+	Type       string                 `json:"type"`
+	Geometry   Geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Geometry is a Point, whose coordinates are one position, or a LineString,
+// whose coordinates are a list of them. Positions are longitude, latitude
+// and altitude in meters.
+type Geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// GeoJSON turns flights into a Point feature for their latest position and
+// a LineString feature for their trail, as opts asks. Trail features carry
+// the time of every coordinate in coordTimes, as togeojson and most viewers
+// with a time slider expect, and its flight phase in phases.
+func GeoJSON(flights []model.Flight, opts Options) *FeatureCollection {
+	fc := &FeatureCollection{Type: "FeatureCollection", Features: []Feature{}}
+	for i := range flights {
+		f := &flights[i]
+		if opts.Positions && f.Position != nil {
+			fc.Features = append(fc.Features, positionFeature(f, opts))
+		}
+		if opts.Trails {
+			if feature, ok := trailFeature(f, opts); ok {
+				fc.Features = append(fc.Features, feature)
+			}
+		}
+	}
+	return fc
+}
+
+// WriteGeoJSON writes flights as one FeatureCollection.
+func WriteGeoJSON(w io.Writer, flights []model.Flight, opts Options) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(GeoJSON(flights, opts))
+}
+
+func positionFeature(f *model.Flight, opts Options) Feature {
+	p := f.Position
+	props := properties(f)
+	props["feature"] = "position"
+	props["time"] = p.Time.Format(time.RFC3339)
+	altitude, speed, verticalRate := p.In(opts.Units)
+	props["altitude"] = altitude.Value
+	props["altitude_unit"] = altitude.Unit
+	props["speed"] = speed.Value
+	props["speed_unit"] = speed.Unit
+	props["vertical_rate"] = verticalRate.Value
+	props["vertical_rate_unit"] = verticalRate.Unit
+	props["track"] = p.Track
+	props["on_ground"] = p.OnGround
+	if p.Squawk != "" {
+		props["squawk"] = p.Squawk
+	}
+	return Feature{
+		Type:       "Feature",
+		Geometry:   Geometry{Type: "Point", Coordinates: coordinate(*p)},
+		Properties: props,
+	}
+}
+
+// trailFeature needs at least two positions, the least a LineString takes.
+func trailFeature(f *model.Flight, opts Options) (Feature, bool) {
+	trail := opts.reduce(f.Trail)
+	if len(trail) < 2 {
+		return Feature{}, false
+	}
+	coordinates := make([][]float64, len(trail))
+	times := make([]string, len(trail))
+	phases := make([]string, len(trail))
+	var maxAltitude float64
+	for i, p := range trail {
+		coordinates[i] = coordinate(p)
+		times[i] = p.Time.Format(time.RFC3339)
+		phases[i] = p.Phase
+		if p.AltitudeM > maxAltitude {
+			maxAltitude = p.AltitudeM
+		}
+	}
+	props := properties(f)
+	props["feature"] = "trail"
+	props["start"] = times[0]
+	props["end"] = times[len(times)-1]
+	props["coordTimes"] = times
+	props["phases"] = phases
+	// The trail's altitude is the latest one, like the position's; the
+	// highest is given too since a landed flight's latest is zero.
+	altitude, _, _ := trail[len(trail)-1].In(opts.Units)
+	props["altitude"] = altitude.Value
+	props["altitude_unit"] = altitude.Unit
+	props["max_altitude"] = opts.Units.Altitude(maxAltitude).Value
+	props["points"] = len(trail)
+	return Feature{
+		Type:       "Feature",
+		Geometry:   Geometry{Type: "LineString", Coordinates: coordinates},
+		Properties: props,
+	}, true
+}
+
+// coordinate keeps six decimals of a degree, about 10 cm, as RFC 7946
+// suggests.
+func coordinate(p model.Position) []float64 {
+	return []float64{units.Round(p.Lon, 6), units.Round(p.Lat, 6), p.AltitudeM}
+}
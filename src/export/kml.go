@@ -0,0 +1,343 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"radar/model"
+)
+
+// aircraftIcon is Google Earth's own airport shape, used by plain KML
+// files; KMZ files embed kmzIcon instead.
+const aircraftIcon = "http://maps.google.com/mapfiles/kml/shapes/airports.png"
+
+type kml struct {
+	XMLName  xml.Name    `xml:"kml"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	XmlnsGx  string      `xml:"xmlns:gx,attr"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name    string      `xml:"name"`
+	Styles  []kmlStyle  `xml:"Style"`
+	Folders []kmlFolder `xml:"Folder"`
+}
+
+type kmlStyle struct {
+	ID        string        `xml:"id,attr,omitempty"`
+	IconStyle *kmlIconStyle `xml:"IconStyle,omitempty"`
+	LineStyle *kmlLineStyle `xml:"LineStyle,omitempty"`
+	PolyStyle *kmlPolyStyle `xml:"PolyStyle,omitempty"`
+}
+
+type kmlIconStyle struct {
+	Color   string   `xml:"color"`
+	Scale   float64  `xml:"scale"`
+	Heading *int     `xml:"heading,omitempty"`
+	Icon    kmlHref  `xml:"Icon"`
+	Hotspot *kmlSpot `xml:"hotSpot,omitempty"`
+}
+
+type kmlHref struct {
+	Href string `xml:"href"`
+}
+
+type kmlSpot struct {
+	X      float64 `xml:"x,attr"`
+	Y      float64 `xml:"y,attr"`
+	XUnits string  `xml:"xunits,attr"`
+	YUnits string  `xml:"yunits,attr"`
+}
+
+type kmlLineStyle struct {
+	Color string  `xml:"color"`
+	Width float64 `xml:"width"`
+}
+
+type kmlPolyStyle struct {
+	Color string `xml:"color"`
+}
+
+type kmlFolder struct {
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name        string      `xml:"name"`
+	Description string      `xml:"description,omitempty"`
+	TimeStamp   *kmlWhen    `xml:"TimeStamp,omitempty"`
+	StyleURL    string      `xml:"styleUrl,omitempty"`
+	Style       *kmlStyle   `xml:"Style,omitempty"`
+	Data        []kmlData   `xml:"ExtendedData>Data"`
+	Point       *kmlPoint   `xml:"Point,omitempty"`
+	Track       *kmlGxTrack `xml:"gx:Track,omitempty"`
+}
+
+type kmlWhen struct {
+	When string `xml:"when"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlPoint struct {
+	Extrude      int    `xml:"extrude"`
+	AltitudeMode string `xml:"altitudeMode"`
+	Coordinates  string `xml:"coordinates"`
+}
+
+// kmlGxTrack is a time-stamped track, which Google Earth plays back with its
+// time slider.
+type kmlGxTrack struct {
+	Extrude      int      `xml:"extrude"`
+	AltitudeMode string   `xml:"altitudeMode"`
+	When         []string `xml:"when"`
+	Coord        []string `xml:"gx:coord"`
+}
+
+// WriteKML writes flights as a KML document for Google Earth: one folder
+// per airline, each flight's trail as an extruded track at absolute
+// altitude with a timestamp per point for the time slider, and an aircraft
+// placemark at its latest position turned to its track. Every airline gets
+// its own color.
+func WriteKML(w io.Writer, flights []model.Flight, opts Options) error {
+	return writeKML(w, flights, opts, aircraftIcon)
+}
+
+func writeKML(w io.Writer, flights []model.Flight, opts Options, icon string) error {
+	doc := kml{
+		Xmlns:   "http://www.opengis.net/kml/2.2",
+		XmlnsGx: "http://www.google.com/kml/ext/2.2",
+	}
+	doc.Document.Name = "radar export"
+
+	folders := make(map[string]*kmlFolder)
+	var keys []string
+	for i := range flights {
+		f := &flights[i]
+		key := airline(f)
+		folder, ok := folders[key]
+		if !ok {
+			folder = &kmlFolder{Name: airlineName(f, key)}
+			folders[key] = folder
+			keys = append(keys, key)
+			doc.Document.Styles = append(doc.Document.Styles, airlineStyle(key, icon))
+		}
+		if opts.Trails {
+			if p, ok := trailPlacemark(f, key, opts); ok {
+				folder.Placemarks = append(folder.Placemarks, p)
+			}
+		}
+		if opts.Positions && f.Position != nil {
+			folder.Placemarks = append(folder.Placemarks, aircraftPlacemark(f, key, icon, opts))
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		doc.Document.Folders = append(doc.Document.Folders, *folders[key])
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func trailPlacemark(f *model.Flight, key string, opts Options) (kmlPlacemark, bool) {
+	trail := opts.reduce(f.Trail)
+	if len(trail) < 2 {
+		return kmlPlacemark{}, false
+	}
+	track := &kmlGxTrack{Extrude: 1, AltitudeMode: "absolute"}
+	for _, p := range trail {
+		track.When = append(track.When, p.Time.Format(time.RFC3339))
+		track.Coord = append(track.Coord, fmt.Sprintf("%.6f %.6f %.1f", p.Lon, p.Lat, p.AltitudeM))
+	}
+	return kmlPlacemark{
+		Name:        title(f),
+		Description: description(f, opts),
+		StyleURL:    "#" + styleID(key),
+		Data:        extendedData(f),
+		Track:       track,
+	}, true
+}
+
+// aircraftPlacemark carries the airline style inline, since the heading
+// differs per aircraft.
+func aircraftPlacemark(f *model.Flight, key, icon string, opts Options) kmlPlacemark {
+	p := f.Position
+	style := airlineStyle(key, icon)
+	style.ID = ""
+	heading := p.Track
+	style.IconStyle.Heading = &heading
+	style.LineStyle, style.PolyStyle = nil, nil
+	return kmlPlacemark{
+		Name:        title(f),
+		Description: description(f, opts),
+		TimeStamp:   &kmlWhen{When: p.Time.Format(time.RFC3339)},
+		Style:       &style,
+		Data:        extendedData(f),
+		Point: &kmlPoint{
+			Extrude:      1,
+			AltitudeMode: "absolute",
+			Coordinates:  fmt.Sprintf("%.6f,%.6f,%.1f", p.Lon, p.Lat, p.AltitudeM),
+		},
+	}
+}
+
+// airlineStyle colors an airline's icons, tracks and the walls extruded
+// below them. The color comes from a hash of the airline so it stays the
+// same across exports.
+func airlineStyle(key, icon string) kmlStyle {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	r, g, b := hueRGB(float64(h.Sum32()%360) / 360)
+	// KML colors are aabbggrr.
+	color := fmt.Sprintf("%02x%02x%02x", b, g, r)
+	return kmlStyle{
+		ID: styleID(key),
+		IconStyle: &kmlIconStyle{
+			Color: "ff" + color,
+			Scale: 1.1,
+			Icon:  kmlHref{Href: icon},
+			Hotspot: &kmlSpot{
+				X: 0.5, Y: 0.5, XUnits: "fraction", YUnits: "fraction",
+			},
+		},
+		LineStyle: &kmlLineStyle{Color: "ff" + color, Width: 2},
+		PolyStyle: &kmlPolyStyle{Color: "40" + color},
+	}
+}
+
+// hueRGB is a fully saturated color of hue h in [0, 1), darkened a little
+// so it stands out on satellite imagery and in the extruded walls.
+func hueRGB(h float64) (r, g, b uint8) {
+	const v = 230
+	x := 1 - math.Abs(math.Mod(h*6, 2)-1)
+	var rf, gf, bf float64
+	switch int(h * 6) {
+	case 0:
+		rf, gf = 1, x
+	case 1:
+		rf, gf = x, 1
+	case 2:
+		gf, bf = 1, x
+	case 3:
+		gf, bf = x, 1
+	case 4:
+		rf, bf = x, 1
+	default:
+		rf, bf = 1, x
+	}
+	return uint8(rf * v), uint8(gf * v), uint8(bf * v)
+}
+
+func styleID(key string) string {
+	if key == "" {
+		return "airline-unknown"
+	}
+	return "airline-" + key
+}
+
+// airline is the key flights are grouped and styled by: the airline's ICAO
+// code, or the callsign's when the record names no airline. Callsigns that
+// do not start with a three letter designator, such as registrations,
+// give no key.
+func airline(f *model.Flight) string {
+	if f.Airline != nil && f.Airline.Icao != "" {
+		return f.Airline.Icao
+	}
+	callsign := strings.ToUpper(f.Callsign)
+	if len(callsign) < 4 {
+		return ""
+	}
+	for i := 0; i < 3; i++ {
+		if callsign[i] < 'A' || callsign[i] > 'Z' {
+			return ""
+		}
+	}
+	if c := callsign[3]; c < '0' || c > '9' {
+		return ""
+	}
+	return callsign[:3]
+}
+
+func airlineName(f *model.Flight, key string) string {
+	switch {
+	case f.Airline != nil && f.Airline.Name != "":
+		return f.Airline.Name
+	case key != "":
+		return key
+	}
+	return "Unknown airline"
+}
+
+func title(f *model.Flight) string {
+	for _, name := range []string{f.Callsign, f.Number, f.Aircraft.Registration} {
+		if name != "" {
+			return name
+		}
+	}
+	return f.ID
+}
+
+// description is the balloon text: what flew, where, and how high.
+func description(f *model.Flight, opts Options) string {
+	var lines []string
+	add := func(label, value string) {
+		if value != "" {
+			lines = append(lines, label+": "+value)
+		}
+	}
+	add("Registration", f.Aircraft.Registration)
+	add("Aircraft", strings.TrimSpace(f.Aircraft.TypeCode+" "+f.Aircraft.TypeName))
+	if f.Airline != nil {
+		add("Airline", f.Airline.Name)
+	}
+	var route []string
+	for _, a := range []*model.Airport{f.Origin, f.Destination} {
+		if a != nil {
+			route = append(route, a.Code())
+		}
+	}
+	add("Route", strings.Join(route, " - "))
+	if p := f.Position; p != nil {
+		altitude, speed, _ := p.In(opts.Units)
+		add("Altitude", altitude.String())
+		add("Speed", speed.String())
+		add("Seen", p.Time.Format(time.RFC3339))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// extendedData is the same properties the GeoJSON export carries, sorted
+// so exports of the same data are identical.
+func extendedData(f *model.Flight) []kmlData {
+	props := properties(f)
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	data := make([]kmlData, len(names))
+	for i, name := range names {
+		data[i] = kmlData{Name: name, Value: fmt.Sprint(props[name])}
+	}
+	return data
+}
@@ -0,0 +1,89 @@
+package export
+
+import (
+	"archive/zip"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"time"
+
+	"radar/model"
+)
+
+// kmzIcon is where the aircraft icon is stored inside a KMZ.
+const kmzIcon = "files/aircraft.png"
+
+// iconSize is the width and height of the aircraft icon in pixels.
+const iconSize = 64
+
+// aircraftShape is the outline of the aircraft icon, nose up, on a 64 pixel
+// square.
+var aircraftShape = [][2]float64{
+	{32, 2}, {35, 8}, {35, 24}, {60, 38}, {60, 43}, {35, 36}, {35, 52}, {44, 58}, {44, 62},
+	{32, 59}, {20, 62}, {20, 58}, {29, 52}, {29, 36}, {4, 43}, {4, 38}, {29, 24}, {29, 8},
+}
+
+// WriteKMZ writes the KML document of WriteKML zipped together with the
+// aircraft icon, so the file opens the same without network access.
+func WriteKMZ(w io.Writer, flights []model.Flight, opts Options) error {
+	zw := zip.NewWriter(w)
+	// Google Earth reads the first .kml entry of the archive.
+	doc, err := create(zw, "doc.kml")
+	if err != nil {
+		return err
+	}
+	if err := writeKML(doc, flights, opts, kmzIcon); err != nil {
+		return err
+	}
+	icon, err := create(zw, kmzIcon)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(icon, aircraftImage()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func create(zw *zip.Writer, name string) (io.Writer, error) {
+	return zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+}
+
+// aircraftImage draws aircraftShape in white on a transparent background,
+// so the airline colors of the icon style tint it. Edges are antialiased by
+// sampling every pixel 4x4 times.
+func aircraftImage() image.Image {
+	const samples = 4
+	img := image.NewNRGBA(image.Rect(0, 0, iconSize, iconSize))
+	for y := 0; y < iconSize; y++ {
+		for x := 0; x < iconSize; x++ {
+			covered := 0
+			for sy := 0; sy < samples; sy++ {
+				for sx := 0; sx < samples; sx++ {
+					px := float64(x) + (float64(sx)+0.5)/samples
+					py := float64(y) + (float64(sy)+0.5)/samples
+					if inShape(px, py) {
+						covered++
+					}
+				}
+			}
+			if covered > 0 {
+				img.SetNRGBA(x, y, color.NRGBA{255, 255, 255, uint8(covered * 255 / (samples * samples))})
+			}
+		}
+	}
+	return img
+}
+
+// inShape is an even-odd test of the point against aircraftShape.
+func inShape(x, y float64) bool {
+	in := false
+	for i, j := 0, len(aircraftShape)-1; i < len(aircraftShape); j, i = i, i+1 {
+		a, b := aircraftShape[i], aircraftShape[j]
+		if (a[1] > y) != (b[1] > y) && x < (b[0]-a[0])*(y-a[1])/(b[1]-a[1])+a[0] {
+			in = !in
+		}
+	}
+	return in
+}
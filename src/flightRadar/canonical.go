@@ -0,0 +1,184 @@
+package flightRadar
+
+import (
+	"fmt"
+	"time"
+
+	"radar/classify"
+	"radar/model"
+)
+
+// The converters live here rather than in package model so that model does
+// not depend on any source and the sweep can emit canonical records itself.
+
+// Canonical converts a feed entry. Airports only carry their IATA code.
+func (f *FeedFlight) Canonical() model.Flight {
+	flight := model.Flight{
+		Source:   model.SourceFR24,
+		ID:       f.ID,
+		Callsign: f.Callsign,
+		Number:   f.Flight,
+		Aircraft: model.Aircraft{
+			Hex:          f.Hex,
+			Registration: f.Registration,
+			TypeCode:     f.Model,
+		},
+		Live:     true,
+		Position: f.Position(),
+		Updated:  time.Unix(f.Timestamp, 0).UTC(),
+	}
+	if f.AirlineIcao != "" {
+		flight.Airline = &model.Operator{Icao: f.AirlineIcao}
+	}
+	if f.Origin != "" {
+		flight.Origin = &model.Airport{Iata: f.Origin}
+	}
+	if f.Destination != "" {
+		flight.Destination = &model.Airport{Iata: f.Destination}
+	}
+	flight.Class = classify.Flight(&flight).Class
+	return flight
+}
+
+// Position is the feed entry as a canonical position report.
+func (f *FeedFlight) Position() *model.Position {
+	p := &model.Position{
+		Time:         time.Unix(f.Timestamp, 0).UTC(),
+		Lat:          f.Lat,
+		Lon:          f.Lon,
+		Altitude:     f.Altitude,
+		GroundSpeed:  f.Speed,
+		Track:        f.Track,
+		VerticalRate: f.VerticalRate,
+		OnGround:     f.OnGround,
+		Squawk:       f.Squawk,
+	}
+	p.Normalize()
+	return p
+}
+
+// Canonical converts a clickhandler record. The trail is reversed so it runs
+// oldest first and its last point becomes Position.
+func (d *FlightDetails) Canonical() model.Flight {
+	flight := model.Flight{
+		Source:   model.SourceFR24,
+		ID:       d.Identification.ID,
+		Callsign: d.Identification.Callsign,
+		Number:   d.Identification.Number.Default,
+		Aircraft: model.Aircraft{
+			Hex:          d.Aircraft.Hex,
+			Registration: d.Aircraft.Registration,
+			TypeCode:     d.Aircraft.Model.Code,
+			TypeName:     d.Aircraft.Model.Text,
+			CountryID:    d.Aircraft.CountryID,
+		},
+		Origin:      d.Airport.Origin.Canonical(),
+		Destination: d.Airport.Destination.Canonical(),
+		Status:      d.Status.Text,
+		Live:        d.Status.Live,
+		Times: model.Times{
+			ScheduledDeparture: d.Time.Scheduled.DepartureTime(),
+			ScheduledArrival:   d.Time.Scheduled.ArrivalTime(),
+			EstimatedDeparture: d.Time.Estimated.DepartureTime(),
+			EstimatedArrival:   d.Time.Estimated.ArrivalTime(),
+			ActualDeparture:    d.Time.Real.DepartureTime(),
+			ActualArrival:      d.Time.Real.ArrivalTime(),
+			Eta:                unixTime(d.Time.Other.Eta),
+		},
+	}
+	if msn, ok := d.Aircraft.Msn.(string); ok {
+		flight.Aircraft.Msn = msn
+	} else if d.Aircraft.Msn != nil {
+		flight.Aircraft.Msn = fmt.Sprint(d.Aircraft.Msn)
+	}
+	images := d.Aircraft.Images
+	for _, set := range []struct {
+		size   string
+		images []Image
+	}{
+		{model.PhotoLarge, images.Large},
+		{model.PhotoMedium, images.Medium},
+		{model.PhotoThumbnail, images.Thumbnails},
+	} {
+		for _, img := range set.images {
+			flight.Aircraft.Photos = append(flight.Aircraft.Photos, model.Photo{
+				URL:          img.Src,
+				Size:         set.size,
+				Link:         img.Link,
+				Photographer: img.Copyright,
+				Source:       img.Source,
+			})
+		}
+	}
+	flight.Delays = flight.Times.Delays()
+	flight.Airline = d.Airline.Canonical()
+	flight.Owner = d.Owner.Canonical()
+	if actual := d.Airport.Real.Canonical(); actual != nil && (flight.Destination == nil || actual.Code() != flight.Destination.Code()) {
+		flight.Diverted = actual
+	}
+
+	for i := len(d.Trail) - 1; i >= 0; i-- {
+		flight.Trail = append(flight.Trail, d.Trail[i].Canonical())
+	}
+	if n := len(flight.Trail); n > 0 {
+		last := flight.Trail[n-1]
+		flight.Position = &last
+		flight.Updated = last.Time
+	}
+	if updated := unixTime(d.Time.Other.Updated); updated != nil && updated.After(flight.Updated) {
+		flight.Updated = *updated
+	}
+	flight.Class = classify.Flight(&flight).Class
+	return flight
+}
+
+// Canonical converts a trail point. Trail points carry no vertical rate.
+func (p TrailPoint) Canonical() model.Position {
+	pos := model.Position{
+		Time:        time.Unix(p.Ts, 0).UTC(),
+		Lat:         p.Lat,
+		Lon:         p.Lng,
+		Altitude:    p.Alt,
+		GroundSpeed: p.Spd,
+		Track:       p.Hd,
+		OnGround:    p.Alt == 0,
+		Phase:       p.Phase,
+	}
+	pos.Normalize()
+	return pos
+}
+
+func (a *AirportRef) Canonical() *model.Airport {
+	if a == nil || (a.Code.Iata == "" && a.Code.Icao == "") {
+		return nil
+	}
+	return &model.Airport{
+		Iata:        a.Code.Iata,
+		Icao:        a.Code.Icao,
+		Name:        a.Name,
+		Lat:         a.Position.Latitude,
+		Lon:         a.Position.Longitude,
+		Elevation:   a.Position.Altitude,
+		City:        a.Position.Region.City,
+		Country:     a.Position.Country.Name,
+		CountryCode: a.Position.Country.Code,
+		Timezone:    a.Timezone.Name,
+		UTCOffset:   a.Timezone.Offset,
+	}
+}
+
+func (o *Operator) Canonical() *model.Operator {
+	if o == nil || (o.Name == "" && o.Code.Icao == "" && o.Code.Iata == "") {
+		return nil
+	}
+	return &model.Operator{Name: o.Name, Iata: o.Code.Iata, Icao: o.Code.Icao}
+}
+
+// unixTime treats null and 0, which FR24 uses for unknown, alike.
+func unixTime(t *int64) *time.Time {
+	if t == nil || *t == 0 {
+		return nil
+	}
+	u := time.Unix(*t, 0).UTC()
+	return &u
+}
@@ -0,0 +1,138 @@
+package flightRadar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	http "github.com/bogdanfinn/fhttp"
+	tls_client "github.com/bogdanfinn/tls-client"
+	"github.com/bogdanfinn/tls-client/profiles"
+)
+
+const (
+	feedURL         = "https://data-cloud.flightradar24.com/zones/fcgi/feed.js"
+	clickhandlerURL = "https://data-live.flightradar24.com/clickhandler/"
+)
+
+// Client wraps the tls-client used for every FR24 request so the browser
+// headers are set in one place.
+//
+// note: using tls-client package to imporsonate TLS fingerprinting to bypass cloudflare's restrictions
+type Client struct {
+	HTTP tls_client.HttpClient
+
+	// Retries is how many times a clickhandler request is attempted while
+	// FR24 answers with a non-200 status.
+	Retries    int
+	RetryDelay time.Duration
+}
+
+func NewClient() (*Client, error) {
+	jar := tls_client.NewCookieJar()
+	options := []tls_client.HttpClientOption{
+		tls_client.WithTimeoutSeconds(30),
+		tls_client.WithClientProfile(profiles.Chrome_120),
+		tls_client.WithNotFollowRedirects(),
+		tls_client.WithCookieJar(jar), // create cookieJar instance and pass it as argument
+	}
+	client, err := tls_client.NewHttpClient(tls_client.NewNoopLogger(), options...)
+	if err != nil {
+		return nil, err
+	}
+	//client.SetProxy("http://127.0.0.1:8080")
+	return &Client{HTTP: client, Retries: 20, RetryDelay: 3 * time.Second}, nil
+}
+
+func (c *Client) newRequest(ctx context.Context, reqURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("accept-encoding", "gzip, br")
+	req.Header.Set("accept-language", "pt-BR,pt;q=0.9,en-US;q=0.8,en;q=0.7")
+	req.Header.Set("cache-control", "max-age=0")
+	req.Header.Set("origin", "https://www.flightradar24.com")
+	req.Header.Set("referer", "https://www.flightradar24.com/")
+	req.Header.Set("sec-fetch-dest", "empty")
+	req.Header.Set("sec-fetch-mode", "cors")
+	req.Header.Set("sec-fetch-site", "same-site")
+	req.Header.Set("user-agent", "Mozilla/5.0 (Windows NT 6.1) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/87.0.4280.88 Safari/537.36")
+	return req, nil
+}
+
+// get performs a single GET and returns the body of a 200 response.
+func (c *Client) get(ctx context.Context, reqURL string) ([]byte, error) {
+	req, err := c.newRequest(ctx, reqURL)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return nil, &StatusError{URL: reqURL, StatusCode: res.StatusCode}
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+// Fetch returns the body of any URL, e.g. an aircraft photo, through the
+// same browser impersonating client.
+func (c *Client) Fetch(ctx context.Context, reqURL string) ([]byte, error) {
+	return c.get(ctx, reqURL)
+}
+
+// StatusError is returned when FR24 answers with anything but 200.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned status %d", e.URL, e.StatusCode)
+}
+
+// fetchFlightDetail returns the raw clickhandler body for a flight ID,
+// retrying while FR24 rate limits us.
+func (c *Client) fetchFlightDetail(ctx context.Context, flightID string) ([]byte, error) {
+	reqURL := fmt.Sprintf("%s?flight=%s", clickhandlerURL, flightID)
+	attempts := c.Retries
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for retry := 0; retry < attempts; retry++ {
+		var body []byte
+		body, err = c.get(ctx, reqURL)
+		if err == nil {
+			return body, nil
+		}
+		if _, ok := err.(*StatusError); !ok {
+			return nil, err
+		}
+		fmt.Println("[INF] Retrying...", retry)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.RetryDelay):
+		}
+	}
+	return nil, err
+}
+
+// FlightDetail fetches and decodes the clickhandler record of a live flight.
+func (c *Client) FlightDetail(ctx context.Context, flightID string) (*FlightDetails, error) {
+	body, err := c.fetchFlightDetail(ctx, flightID)
+	if err != nil {
+		return nil, err
+	}
+	var details FlightDetails
+	if err := json.Unmarshal(body, &details); err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
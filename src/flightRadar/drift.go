@@ -0,0 +1,296 @@
+package flightRadar
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// driftMetric counts drift occurrences by "<source>.<kind>", published on
+// /debug/vars as fr24_schema_drift (see radar's -metrics flag).
+var driftMetric = expvar.NewMap("fr24_schema_drift")
+
+const (
+	DriftUnknownField = "unknown_field"
+	DriftTypeMismatch = "type_mismatch"
+	DriftFieldCount   = "field_count"
+)
+
+// maxDriftSample caps how much of an offending value is kept.
+const maxDriftSample = 200
+
+// Drift is one way a response differed from the schema we decode it with.
+type Drift struct {
+	Source    string    `json:"source"`
+	Path      string    `json:"path"`
+	Kind      string    `json:"kind"`
+	Expected  string    `json:"expected,omitempty"`
+	Got       string    `json:"got,omitempty"`
+	Sample    string    `json:"sample"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+type DriftReport struct {
+	Generated time.Time `json:"generated"`
+	Checked   int       `json:"checked"`
+	Drifts    []Drift   `json:"drifts"`
+}
+
+// DriftChecker compares raw FR24 responses against the types they are
+// decoded into. It only records what it finds, callers never fail on it.
+type DriftChecker struct {
+	mu      sync.Mutex
+	checked int
+	drifts  map[string]*Drift
+}
+
+func NewDriftChecker() *DriftChecker {
+	return &DriftChecker{drifts: make(map[string]*Drift)}
+}
+
+// CheckDetails checks a clickhandler body against FlightDetails.
+func (c *DriftChecker) CheckDetails(body []byte) {
+	c.check("clickhandler", body, reflect.TypeOf(FlightDetails{}))
+}
+
+// CheckFeed checks a feed.js body: the top level keys and the positional
+// layout of every flight array.
+func (c *DriftChecker) CheckFeed(body []byte) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		c.record("feed", "", DriftTypeMismatch, "object", "invalid json", string(body))
+		return
+	}
+	c.mu.Lock()
+	c.checked++
+	c.mu.Unlock()
+
+	for key, value := range raw {
+		fields, ok := value.([]interface{})
+		if !ok {
+			if !knownFeedKeys[key] {
+				c.record("feed", key, DriftUnknownField, "", jsonKind(value), sample(value))
+			}
+			continue
+		}
+		if len(fields) != len(feedSchema) {
+			c.record("feed", "flight", DriftFieldCount, fmt.Sprint(len(feedSchema)), fmt.Sprint(len(fields)), sample(value))
+		}
+		for i, field := range fields {
+			if i >= len(feedSchema) || field == nil {
+				continue
+			}
+			if got := jsonKind(field); got != feedSchema[i] {
+				c.record("feed", fmt.Sprintf("flight[%d]", i), DriftTypeMismatch, feedSchema[i], got, sample(field))
+			}
+		}
+	}
+}
+
+var knownFeedKeys = map[string]bool{
+	"full_count": true,
+	"version":    true,
+	"stats":      true,
+}
+
+// feedSchema is the JSON kind of every position FeedFlight decodes.
+var feedSchema = [feedFields]string{
+	"string", "number", "number", "number", "number", "number", "string", "string", "string", "string",
+	"number", "string", "string", "string", "number", "number", "string", "number", "string",
+}
+
+func (c *DriftChecker) check(source string, body []byte, t reflect.Type) {
+	var raw interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		c.record(source, "", DriftTypeMismatch, "object", "invalid json", string(body))
+		return
+	}
+	c.mu.Lock()
+	c.checked++
+	c.mu.Unlock()
+	c.walk(source, "", raw, t)
+}
+
+func (c *DriftChecker) walk(source, path string, value interface{}, t reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if value == nil || t.Kind() == reflect.Interface {
+		return
+	}
+	// Types that decode themselves accept more than one shape.
+	if reflect.PtrTo(t).Implements(reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()) {
+		return
+	}
+
+	want := schemaKind(t)
+	got := jsonKind(value)
+	if want != got && !(want == "integer" && got == "number" && isIntegral(value)) {
+		c.record(source, path, DriftTypeMismatch, want, got, sample(value))
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := jsonFields(t)
+		for key, v := range value.(map[string]interface{}) {
+			ft, ok := fields[key]
+			if !ok {
+				c.record(source, joinPath(path, key), DriftUnknownField, "", jsonKind(v), sample(v))
+				continue
+			}
+			c.walk(source, joinPath(path, key), v, ft)
+		}
+	case reflect.Map:
+		for _, v := range value.(map[string]interface{}) {
+			c.walk(source, path+"{}", v, t.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for _, v := range value.([]interface{}) {
+			c.walk(source, path+"[]", v, t.Elem())
+		}
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// jsonFields maps the JSON names of a struct (including embedded ones) to
+// their types.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for k, v := range jsonFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func schemaKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return t.Kind().String()
+}
+
+func jsonKind(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func isIntegral(v interface{}) bool {
+	f, ok := v.(float64)
+	return ok && f == math.Trunc(f)
+}
+
+func sample(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	if len(b) > maxDriftSample {
+		return string(b[:maxDriftSample]) + "..."
+	}
+	return string(b)
+}
+
+func (c *DriftChecker) record(source, path, kind, expected, got, value string) {
+	if len(value) > maxDriftSample {
+		value = value[:maxDriftSample] + "..."
+	}
+	key := source + "|" + path + "|" + kind + "|" + got
+	now := time.Now().UTC()
+
+	c.mu.Lock()
+	d, ok := c.drifts[key]
+	if !ok {
+		d = &Drift{Source: source, Path: path, Kind: kind, Expected: expected, Got: got, FirstSeen: now}
+		c.drifts[key] = d
+		fmt.Println("[INF] Schema drift:", source, path, kind, expected, got)
+	}
+	d.Count++
+	d.Sample = value
+	d.LastSeen = now
+	c.mu.Unlock()
+
+	driftMetric.Add(source+"."+kind, 1)
+}
+
+// Report returns everything recorded so far, most frequent first.
+func (c *DriftChecker) Report() DriftReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	report := DriftReport{Generated: time.Now().UTC(), Checked: c.checked}
+	for _, d := range c.drifts {
+		report.Drifts = append(report.Drifts, *d)
+	}
+	sort.Slice(report.Drifts, func(i, j int) bool {
+		if report.Drifts[i].Count != report.Drifts[j].Count {
+			return report.Drifts[i].Count > report.Drifts[j].Count
+		}
+		return report.Drifts[i].Path < report.Drifts[j].Path
+	})
+	return report
+}
+
+// WriteReport saves the current report as JSON.
+func (c *DriftChecker) WriteReport(file string) error {
+	body, err := json.MarshalIndent(c.Report(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, body, 0644)
+}
@@ -0,0 +1,49 @@
+package flightRadar
+
+import (
+	"slices"
+	"sort"
+	"testing"
+)
+
+const feedEntry = `["3C6589",50.1,8.6,90,35000,450,"1000","F-EDDF1","A320","D-AIBL",1714500000,"FRA","LHR","LH900",0,0,"DLH900",0,"DLH"]`
+
+func TestDriftChecker(t *testing.T) {
+	tests := []struct {
+		name    string
+		details bool // CheckDetails instead of CheckFeed
+		body    string
+		want    []string // source|path|kind|got
+	}{
+		{"feed", false, `{"full_count":1,"version":4,"35a1b2c3":` + feedEntry + `}`, nil},
+		{"feed with a new key", false, `{"full_count":1,"selected":"x","35a1b2c3":` + feedEntry + `}`,
+			[]string{"feed|selected|unknown_field|string"}},
+		{"feed with a new field", false, `{"35a1b2c3":["3C6589",50.1,8.6,90,35000,450,"1000","F-EDDF1","A320","D-AIBL",1714500000,"FRA","LHR","LH900",0,0,"DLH900",0,"DLH",1]}`,
+			[]string{"feed|flight|field_count|20"}},
+		{"feed with a string altitude", false, `{"35a1b2c3":["3C6589",50.1,8.6,90,"35000",450,"1000","F-EDDF1","A320","D-AIBL",1714500000,"FRA","LHR","LH900",0,0,"DLH900",0,"DLH"]}`,
+			[]string{"feed|flight[4]|type_mismatch|string"}},
+		{"invalid feed", false, `{"full_count":`, []string{"feed||type_mismatch|invalid json"}},
+		{"details", true, `{"identification":{"id":"35a1b2c3","row":1},"firstTimestamp":1714500000}`, nil},
+		{"details with a new field", true, `{"identification":{"id":"35a1b2c3","tail":"x"}}`,
+			[]string{"clickhandler|identification.tail|unknown_field|string"}},
+		{"details with a float timestamp", true, `{"firstTimestamp":1714500000.5}`,
+			[]string{"clickhandler|firstTimestamp|type_mismatch|number"}},
+		{"invalid details", true, `{"identification":`, []string{"clickhandler||type_mismatch|invalid json"}},
+	}
+	for _, tt := range tests {
+		c := NewDriftChecker()
+		if tt.details {
+			c.CheckDetails([]byte(tt.body))
+		} else {
+			c.CheckFeed([]byte(tt.body))
+		}
+		var got []string
+		for _, d := range c.Report().Drifts {
+			got = append(got, d.Source+"|"+d.Path+"|"+d.Kind+"|"+d.Got)
+		}
+		sort.Strings(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: drifts %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package flightRadar
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// FeedFlight is one aircraft from feed.js. FR24 sends each one as a
+// positional array keyed by flight ID.
+type FeedFlight struct {
+	ID           string  `json:"id"`
+	Hex          string  `json:"hex"`
+	Lat          float64 `json:"lat"`
+	Lon          float64 `json:"lon"`
+	Track        int     `json:"track"`
+	Altitude     int     `json:"altitude"`
+	Speed        int     `json:"speed"`
+	Squawk       string  `json:"squawk"`
+	Radar        string  `json:"radar"`
+	Model        string  `json:"model"`
+	Registration string  `json:"registration"`
+	Timestamp    int64   `json:"timestamp"`
+	Origin       string  `json:"origin"`
+	Destination  string  `json:"destination"`
+	Flight       string  `json:"flight"`
+	OnGround     bool    `json:"on_ground"`
+	VerticalRate int     `json:"vertical_rate"`
+	Callsign     string  `json:"callsign"`
+	Glider       bool    `json:"glider"`
+	AirlineIcao  string  `json:"airline_icao"`
+}
+
+// feedFields is the number of positions FR24 currently sends per flight.
+const feedFields = 19
+
+func (f *FeedFlight) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		type plain FeedFlight
+		return json.Unmarshal(data, (*plain)(f))
+	}
+
+	var fields []interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) < feedFields {
+		return fmt.Errorf("feed flight has %d fields, want %d", len(fields), feedFields)
+	}
+	f.Hex = feedString(fields[0])
+	f.Lat = feedFloat(fields[1])
+	f.Lon = feedFloat(fields[2])
+	f.Track = int(feedFloat(fields[3]))
+	f.Altitude = int(feedFloat(fields[4]))
+	f.Speed = int(feedFloat(fields[5]))
+	f.Squawk = feedString(fields[6])
+	f.Radar = feedString(fields[7])
+	f.Model = feedString(fields[8])
+	f.Registration = feedString(fields[9])
+	f.Timestamp = int64(feedFloat(fields[10]))
+	f.Origin = feedString(fields[11])
+	f.Destination = feedString(fields[12])
+	f.Flight = feedString(fields[13])
+	f.OnGround = feedFloat(fields[14]) != 0
+	f.VerticalRate = int(feedFloat(fields[15]))
+	f.Callsign = feedString(fields[16])
+	f.Glider = feedFloat(fields[17]) != 0
+	f.AirlineIcao = feedString(fields[18])
+	return nil
+}
+
+func feedString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+func feedFloat(v interface{}) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Feed is a decoded feed.js response for one tile.
+type Feed struct {
+	FullCount int          `json:"full_count"`
+	Version   int          `json:"version"`
+	Flights   []FeedFlight `json:"flights"`
+}
+
+// ParseFeed decodes a feed.js body. Entries that are not flights (version,
+// full_count, stats, ...) are skipped.
+func ParseFeed(body []byte) (*Feed, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+
+	feed := &Feed{}
+	for key, value := range raw {
+		switch key {
+		case "full_count":
+			json.Unmarshal(value, &feed.FullCount)
+			continue
+		case "version":
+			json.Unmarshal(value, &feed.Version)
+			continue
+		}
+		if len(value) == 0 || value[0] != '[' {
+			continue
+		}
+		var f FeedFlight
+		if err := json.Unmarshal(value, &f); err != nil {
+			fmt.Println("[Err] Skipping feed entry", key, err)
+			continue
+		}
+		f.ID = key
+		feed.Flights = append(feed.Flights, f)
+	}
+	return feed, nil
+}
@@ -1,20 +1,28 @@
 package flightRadar
 
 import (
-	"encoding/json"
-	"io/ioutil"
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/url"
-	"strconv"
+	"os"
+	"path"
+	"slices"
 	"sync"
-	"path"	
 	"time"
-	"fmt"
-	"os"
+
 	"github.com/redis/go-redis/v9"
-	http "github.com/bogdanfinn/fhttp"
-	tls_client "github.com/bogdanfinn/tls-client"
-	"github.com/bogdanfinn/tls-client/profiles"
+
+	"radar/detect"
+	"radar/eta"
+	"radar/geofence"
+	"radar/icao"
+	"radar/model"
+	"radar/movement"
+	"radar/proximity"
+	"radar/spatial"
+	"radar/stats"
+	"radar/track"
 )
 
 type Bound struct {
@@ -29,13 +37,10 @@ type FlightBounds struct {
 
 type FlightDetails struct {
 	Identification struct {
-		ID     string `json:"id"`
-		Row    int64  `json:"row"`
-		Number struct {
-			Default     interface{} `json:"default"`
-			Alternative interface{} `json:"alternative"`
-		} `json:"number"`
-		Callsign string `json:"callsign"`
+		ID       string       `json:"id"`
+		Row      int64        `json:"row"`
+		Number   FlightNumber `json:"number"`
+		Callsign string       `json:"callsign"`
 	} `json:"identification"`
 	Status struct {
 		Live      bool        `json:"live"`
@@ -62,200 +67,298 @@ type FlightDetails struct {
 		Registration string      `json:"registration"`
 		Age          interface{} `json:"age"`
 		Msn          interface{} `json:"msn"`
-		Images       struct {
-			Thumbnails []struct {
-				Src       string `json:"src"`
-				Link      string `json:"link"`
-				Copyright string `json:"copyright"`
-				Source    string `json:"source"`
-			} `json:"thumbnails"`
-			Medium []struct {
-				Src       string `json:"src"`
-				Link      string `json:"link"`
-				Copyright string `json:"copyright"`
-				Source    string `json:"source"`
-			} `json:"medium"`
-			Large []struct {
-				Src       string `json:"src"`
-				Link      string `json:"link"`
-				Copyright string `json:"copyright"`
-				Source    string `json:"source"`
-			} `json:"large"`
-		} `json:"images"`
-		Hex string `json:"hex"`
+		Images       ImageSet    `json:"images"`
+		Hex          string      `json:"hex"`
 	} `json:"aircraft"`
-	Airline struct {
-		Name  string `json:"name"`
-		Short string `json:"short"`
-		Code  struct {
-			Iata interface{} `json:"iata"`
-			Icao string      `json:"icao"`
-		} `json:"code"`
-		URL string `json:"url"`
-	} `json:"airline"`
-	Owner    interface{} `json:"owner"`
-	Airspace interface{} `json:"airspace"`
+	Airline  Operator  `json:"airline"`
+	Owner    *Operator `json:"owner"`
+	Airspace *Airspace `json:"airspace"`
 	Airport  struct {
-		Origin struct {
-			Name string `json:"name"`
-			Code struct {
-				Iata string `json:"iata"`
-				Icao string `json:"icao"`
-			} `json:"code"`
-			Position struct {
-				Latitude  float64 `json:"latitude"`
-				Longitude float64 `json:"longitude"`
-				Altitude  int     `json:"altitude"`
-				Country   struct {
-					ID   interface{} `json:"id"`
-					Name string      `json:"name"`
-					Code string      `json:"code"`
-				} `json:"country"`
-				Region struct {
-					City string `json:"city"`
-				} `json:"region"`
-			} `json:"position"`
-			Timezone struct {
-				Name        string `json:"name"`
-				Offset      int    `json:"offset"`
-				OffsetHours string `json:"offsetHours"`
-				Abbr        string `json:"abbr"`
-				AbbrName    string `json:"abbrName"`
-				IsDst       bool   `json:"isDst"`
-			} `json:"timezone"`
-			Visible bool        `json:"visible"`
-			Website interface{} `json:"website"`
-			Info    struct {
-				Terminal interface{} `json:"terminal"`
-				Baggage  interface{} `json:"baggage"`
-				Gate     interface{} `json:"gate"`
-			} `json:"info"`
-		} `json:"origin"`
-		Destination interface{} `json:"destination"`
-		Real        interface{} `json:"real"`
+		Origin      *AirportRef `json:"origin"`
+		Destination *AirportRef `json:"destination"`
+		Real        *AirportRef `json:"real"`
 	} `json:"airport"`
 	FlightHistory struct {
-		Aircraft []struct {
-			Identification struct {
-				ID     string `json:"id"`
-				Number struct {
-					Default interface{} `json:"default"`
-				} `json:"number"`
-			} `json:"identification"`
-			Airport struct {
-				Origin struct {
-					Name string `json:"name"`
-					Code struct {
-						Iata string `json:"iata"`
-						Icao string `json:"icao"`
-					} `json:"code"`
-					Position struct {
-						Latitude  float64 `json:"latitude"`
-						Longitude float64 `json:"longitude"`
-						Altitude  int     `json:"altitude"`
-						Country   struct {
-							ID   interface{} `json:"id"`
-							Name string      `json:"name"`
-							Code string      `json:"code"`
-						} `json:"country"`
-						Region struct {
-							City string `json:"city"`
-						} `json:"region"`
-					} `json:"position"`
-					Timezone struct {
-						Name        string `json:"name"`
-						Offset      int    `json:"offset"`
-						OffsetHours string `json:"offsetHours"`
-						Abbr        string `json:"abbr"`
-						AbbrName    string `json:"abbrName"`
-						IsDst       bool   `json:"isDst"`
-					} `json:"timezone"`
-					Visible bool        `json:"visible"`
-					Website interface{} `json:"website"`
-				} `json:"origin"`
-				Destination struct {
-					Name string `json:"name"`
-					Code struct {
-						Iata string `json:"iata"`
-						Icao string `json:"icao"`
-					} `json:"code"`
-					Position struct {
-						Latitude  float64 `json:"latitude"`
-						Longitude float64 `json:"longitude"`
-						Altitude  int     `json:"altitude"`
-						Country   struct {
-							ID   interface{} `json:"id"`
-							Name string      `json:"name"`
-							Code string      `json:"code"`
-						} `json:"country"`
-						Region struct {
-							City string `json:"city"`
-						} `json:"region"`
-					} `json:"position"`
-					Timezone struct {
-						Name        string `json:"name"`
-						Offset      int    `json:"offset"`
-						OffsetHours string `json:"offsetHours"`
-						Abbr        string `json:"abbr"`
-						AbbrName    string `json:"abbrName"`
-						IsDst       bool   `json:"isDst"`
-					} `json:"timezone"`
-					Visible bool        `json:"visible"`
-					Website interface{} `json:"website"`
-				} `json:"destination"`
-			} `json:"airport"`
-			Time struct {
-				Real struct {
-					Departure int `json:"departure"`
-				} `json:"real"`
-			} `json:"time"`
-		} `json:"aircraft"`
+		Aircraft []HistoryFlight `json:"aircraft"`
 	} `json:"flightHistory"`
-	Ems          interface{} `json:"ems"`
-	Availability []string    `json:"availability"`
-	Time         struct {
-		Scheduled struct {
-			Departure int `json:"departure"`
-			Arrival   int `json:"arrival"`
-		} `json:"scheduled"`
-		Real struct {
-			Departure int         `json:"departure"`
-			Arrival   interface{} `json:"arrival"`
-		} `json:"real"`
-		Estimated struct {
-			Departure interface{} `json:"departure"`
-			Arrival   interface{} `json:"arrival"`
-		} `json:"estimated"`
-		Other struct {
-			Eta     int `json:"eta"`
-			Updated int `json:"updated"`
-		} `json:"other"`
-		Historical interface{} `json:"historical"`
+	Ems            interface{}  `json:"ems"`
+	Availability   []string     `json:"availability"`
+	Time           Timestamps   `json:"time"`
+	Trail          []TrailPoint `json:"trail"`
+	FirstTimestamp int          `json:"firstTimestamp"`
+	S              string       `json:"s"`
+}
+
+type FlightNumber struct {
+	Default     string `json:"default"`
+	Alternative string `json:"alternative,omitempty"`
+}
+
+// Operator is used for both the airline and the owner of an aircraft.
+type Operator struct {
+	Name  string `json:"name"`
+	Short string `json:"short,omitempty"`
+	Code  struct {
+		Iata string `json:"iata"`
+		Icao string `json:"icao"`
+	} `json:"code"`
+	URL string `json:"url"`
+}
+
+type Airspace struct {
+	Name string `json:"name"`
+}
+
+// UnmarshalJSON also accepts a bare airspace name.
+func (a *Airspace) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &a.Name)
+	}
+	type plain Airspace
+	return json.Unmarshal(data, (*plain)(a))
+}
+
+type Image struct {
+	Src       string `json:"src"`
+	Link      string `json:"link"`
+	Copyright string `json:"copyright"`
+	Source    string `json:"source"`
+}
+
+type ImageSet struct {
+	Thumbnails []Image `json:"thumbnails"`
+	Medium     []Image `json:"medium"`
+	Large      []Image `json:"large"`
+}
+
+// AirportRef is the airport object clickhandler repeats for origin,
+// destination, real destination and every flight history entry.
+type AirportRef struct {
+	Name string `json:"name"`
+	Code struct {
+		Iata string `json:"iata"`
+		Icao string `json:"icao"`
+	} `json:"code"`
+	Position struct {
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+		Altitude  int     `json:"altitude"`
+		Country   struct {
+			ID   *int   `json:"id"`
+			Name string `json:"name"`
+			Code string `json:"code"`
+		} `json:"country"`
+		Region struct {
+			City string `json:"city"`
+		} `json:"region"`
+	} `json:"position"`
+	Timezone Timezone `json:"timezone"`
+	Visible  bool     `json:"visible"`
+	Website  string   `json:"website"`
+	// Info is only sent for the current flight's airports.
+	Info *struct {
+		Terminal string `json:"terminal"`
+		Baggage  string `json:"baggage"`
+		Gate     string `json:"gate"`
+	} `json:"info,omitempty"`
+}
+
+type Timezone struct {
+	Name        string `json:"name"`
+	Offset      int    `json:"offset"`
+	OffsetHours string `json:"offsetHours"`
+	Abbr        string `json:"abbr"`
+	AbbrName    string `json:"abbrName"`
+	IsDst       bool   `json:"isDst"`
+}
+
+type HistoryFlight struct {
+	Identification struct {
+		ID     string       `json:"id"`
+		Number FlightNumber `json:"number"`
+	} `json:"identification"`
+	Airport struct {
+		Origin      *AirportRef `json:"origin"`
+		Destination *AirportRef `json:"destination"`
+	} `json:"airport"`
+	Time struct {
+		Real TimePair `json:"real"`
 	} `json:"time"`
-	Trail []struct {
-		Lat float64 `json:"lat"`
-		Lng float64 `json:"lng"`
-		Alt int     `json:"alt"`
-		Spd int     `json:"spd"`
-		Ts  int     `json:"ts"`
-		Hd  int     `json:"hd"`
-	} `json:"trail"`
-	FirstTimestamp int    `json:"firstTimestamp"`
-	S              string `json:"s"`
 }
 
-type ApiStruct struct {
-	FullCount int                    `json:"full_count"`
-	Version   int                    `json:"version"`
-	Data      map[string]interface{} `json:"-"`
+// TimePair holds epoch seconds; nil means FR24 sent null.
+type TimePair struct {
+	Departure *int64 `json:"departure"`
+	Arrival   *int64 `json:"arrival"`
+}
+
+// DepartureTime and ArrivalTime return nil when FR24 sent null or 0.
+func (p TimePair) DepartureTime() *time.Time { return unixTime(p.Departure) }
+func (p TimePair) ArrivalTime() *time.Time   { return unixTime(p.Arrival) }
+
+type Timestamps struct {
+	Scheduled TimePair `json:"scheduled"`
+	Real      TimePair `json:"real"`
+	Estimated TimePair `json:"estimated"`
+	Other     struct {
+		Eta      *int64 `json:"eta"`
+		Updated  *int64 `json:"updated"`
+		Duration *int64 `json:"duration,omitempty"`
+	} `json:"other"`
+	Historical *struct {
+		FlightTime string `json:"flighttime"`
+		Delay      string `json:"delay"`
+	} `json:"historical"`
+}
+
+type TrailPoint struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+	Alt int     `json:"alt"`
+	Spd int     `json:"spd"`
+	Ts  int64   `json:"ts"`
+	Hd  int     `json:"hd"`
+	// Phase is not sent by FR24; stored records label their trail with
+	// the flight phase of every point, see package phase.
+	Phase string `json:"phase,omitempty"`
 }
 
 var (
 	flightIDs []string
-	Temp []string
+	Temp      []string
 )
 
-func Start() {
+// Options configures the sweep loop run by Start.
+type Options struct {
+	// Bounds are the tiles requested from feed.js on every sweep.
+	Bounds []Bound
+	// Zones sweeps FR24's named zones instead of Bounds. ZonesFile is a saved
+	// copy of the zone list; it is fetched and written there when missing.
+	Zones     []string
+	ZonesFile string
+
+	// DriftFile receives the schema drift report every DriftEvery
+	// (Data/drift.json and 10 minutes by default).
+	DriftFile  string
+	DriftEvery time.Duration
+
+	// PathTemplate lays records out under Data, see ParsePathTemplate.
+	PathTemplate string
+	// Simplify and Resample reduce the trail of stored records, see package
+	// track. Trail files always keep every point.
+	Simplify track.Tolerance
+	Resample time.Duration
+
+	// Classes limits the records written to Data to these classes (see
+	// package classify). All records are written when empty.
+	Classes []string
+
+	// Squawks are alert codes on top of EmergencySquawks, code to meaning.
+	Squawks map[string]string
+	// OnAlert is called when a flight starts squawking an alert code. Its
+	// details are fetched right away, ahead of the rest of its tile.
+	OnAlert func(alert Alert)
+
+	// OnEvent is called once for every go-around and hold found in a
+	// trail. EventCountsFile receives the counts per airport after every
+	// sweep (Data/airport_events.json by default).
+	OnEvent         func(event detect.Event)
+	EventCountsFile string
+
+	// Geofences are checked against every feed position; OnGeofence gets
+	// the enter, exit and dwell events.
+	Geofences  []*geofence.Fence
+	OnGeofence func(event geofence.Event)
+
+	// Separation enables loss of separation checks between the positions of
+	// each sweep; OnProximity gets the begin and end of every encounter.
+	Separation  proximity.Thresholds
+	OnProximity func(event proximity.Event)
+
+	// Reference fills in the airports and airlines feed entries and
+	// clickhandler records leave out.
+	Reference Reference
+
+	// Airports matches departures and arrivals inferred from ground/air
+	// transitions to airports; OnMovement gets each movement once. It also
+	// places the destinations feed entries only give a code for.
+	Airports   Airports
+	OnMovement func(event movement.Event)
+	// OnEta is called when a flight with arrival estimates (see package
+	// eta) lands, with every estimate set against the actual time.
+	OnEta func(outcome eta.Outcome)
+
+	// Live, when set, is given the positions of every finished sweep so
+	// other parts of the process can query them without a feed request.
+	// Proximity detection queries it too instead of indexing the sweep
+	// again.
+	Live *spatial.Live
+
+	// OnDetail is called with every decoded clickhandler record.
+	OnDetail func(details *FlightDetails)
+	// OnSweep is called after every tile of a sweep has been fetched.
+	OnSweep func()
+}
+
+// Airports is what the sweep needs from an airport directory such as
+// reference.Directory.
+type Airports interface {
+	movement.Airports
+	Airport(code string) (*model.Airport, bool)
+}
+
+// Reference is what the sweep needs from the airport and airline directory,
+// reference.Directory.
+type Reference interface {
+	EnrichFlight(f *model.Flight)
+	EnrichDetails(details *FlightDetails) Enrichment
+}
+
+// sweeper holds what the sweep goroutines share.
+type sweeper struct {
+	opts    Options
+	client  *Client
+	rdb     *redis.Client
+	drift   *DriftChecker
+	squawks *squawkWatch
+	trails  *trails
+	events  *detect.Tracker
+	fences  *geofence.Engine
+	// registry cross-checks addresses, registrations and country IDs.
+	registry  *icao.Checker
+	proximity *proximity.Detector
+	movements *movement.Tracker
+	etas      *eta.Tracker
+
+	// snapshot collects the canonical feed positions of the running sweep.
+	snapshot   []model.Flight
+	snapshotMu sync.Mutex
+	// fetched are the flights whose details the running sweep fetched, so a
+	// flight seen by overlapping tiles (a zone and its subzones) is fetched
+	// once.
+	fetched   map[string]bool
+	fetchedMu sync.Mutex
+
+	dataDir string
+	layout  PathTemplate
+}
+
+// LoadBounds reads a static tile grid such as flightBounds.json.
+func LoadBounds(file string) ([]Bound, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var flightBounds FlightBounds
+	if err := json.NewDecoder(f).Decode(&flightBounds); err != nil {
+		return nil, err
+	}
+	return flightBounds.Bounds, nil
+}
+
+func Start(opts Options) {
 	//ctx := context.Background()
 	opt, err := redis.ParseURL("redis://localhost:6379/1")
 	if err != nil {
@@ -264,197 +367,410 @@ func Start() {
 	rdb := redis.NewClient(opt)
 
 	//err = rdb.FlushDB(ctx).Err()
-    if err != nil {
-        fmt.Println("Could not flush the database: %v", err)
-    }
 	// rdb := redis.NewClient(rdb)
 	currentDir, err := os.Getwd()
 	if _, e := os.Stat(path.Join(currentDir, "Data")); os.IsNotExist(e) {
 		os.Mkdir(path.Join(currentDir, "Data"), 0777)
 	}
 
-	file, err := os.Open("flightRadar/flightBounds.json")
+	client, err := NewClient()
 	if err != nil {
-		fmt.Println("There was an error reading the fligh bounds file (flightBounds.json)")
+		fmt.Println(err)
 		return
 	}
-	defer file.Close()
 
-	var flightBounds FlightBounds
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&flightBounds)
+	layout, err := ParsePathTemplate(opts.PathTemplate)
 	if err != nil {
-		fmt.Println("There was an error")
+		fmt.Println(err)
 		return
 	}
 
-	jar := tls_client.NewCookieJar()
-	options := []tls_client.HttpClientOption{
-		tls_client.WithTimeoutSeconds(30),
-		tls_client.WithClientProfile(profiles.Chrome_120),
-		tls_client.WithNotFollowRedirects(),
-		tls_client.WithCookieJar(jar), // create cookieJar instance and pass it as argument
-		 // create cookieJar instance and pass it as argument
+	bounds := opts.Bounds
+	if len(opts.Zones) > 0 {
+		zones, err := loadOrFetchZones(context.Background(), client, opts.ZonesFile)
+		if err != nil {
+			fmt.Println("There was an error loading the FR24 zones", err)
+			return
+		}
+		if bounds, err = zones.Bounds(opts.Zones...); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	if len(bounds) == 0 {
+		if bounds, err = LoadBounds("flightRadar/flightBounds.json"); err != nil {
+			fmt.Println("There was an error reading the fligh bounds file (flightBounds.json)", err)
+			return
+		}
 	}
-	client, err := tls_client.NewHttpClient(tls_client.NewNoopLogger(), options...)
-	if err != nil {fmt.Println(err);return}
-	//client.SetProxy("http://127.0.0.1:8080")
 
+	if opts.DriftFile == "" {
+		opts.DriftFile = path.Join(currentDir, "Data", "drift.json")
+	}
+	if opts.EventCountsFile == "" {
+		opts.EventCountsFile = path.Join(currentDir, "Data", "airport_events.json")
+	}
+	if opts.DriftEvery == 0 {
+		opts.DriftEvery = 10 * time.Minute
+	}
+
+	s := &sweeper{
+		opts:      opts,
+		client:    client,
+		rdb:       rdb,
+		drift:     NewDriftChecker(),
+		squawks:   newSquawkWatch(opts.Squawks),
+		trails:    newTrails(),
+		events:    detect.NewTracker(),
+		fences:    geofence.NewEngine(opts.Geofences),
+		proximity: proximity.NewDetector(opts.Separation),
+		movements: movement.NewTracker(opts.Airports),
+		etas:      eta.NewTracker(),
+		registry:  icao.NewChecker(),
+		dataDir:   path.Join(currentDir, "Data"),
+		layout:    layout,
+		fetched:   make(map[string]bool),
+	}
+	lastDriftReport := time.Now()
 	for {
 		var wg sync.WaitGroup
 		sem := make(chan struct{}, 4)
-		for _, bound := range flightBounds.Bounds {
+		for _, bound := range bounds {
 			wg.Add(1)
-			go getFlights(bound, client, rdb, &wg, sem)
+			go s.getFlights(bound, &wg, sem)
 		}
 		wg.Wait()
-			Temp = flightIDs
+		snapshot := s.takeSnapshot()
+		var idx *spatial.Index
+		if opts.Live != nil {
+			opts.Live.Replace(snapshot)
+			idx = opts.Live.Index()
+		}
+		for _, event := range s.proximity.Snapshot(snapshot, idx) {
+			fmt.Println("[INF] Proximity", event)
+			if opts.OnProximity != nil {
+				opts.OnProximity(event)
+			}
+		}
+		s.refresh()
+		s.squawks.sweep()
+		s.trails.expire()
+		s.events.Expire(24 * time.Hour)
+		s.movements.Expire(time.Hour)
+		s.etas.Expire(time.Hour)
+		s.geofenceEvents(s.fences.Expire(10 * time.Minute))
+		if err := s.events.WriteCounts(opts.EventCountsFile); err != nil {
+			fmt.Println("[Err] Could not write the airport event counts", err)
+		}
+		s.fetchedMu.Lock()
+		s.fetched = make(map[string]bool)
+		s.fetchedMu.Unlock()
+		Temp = flightIDs
 		flightIDs = []string{}
+		if opts.OnSweep != nil {
+			opts.OnSweep()
+		}
+		if time.Since(lastDriftReport) >= opts.DriftEvery {
+			s.writeDriftReport()
+			lastDriftReport = time.Now()
+		}
+	}
+}
+
+func (s *sweeper) writeDriftReport() {
+	report := s.drift.Report()
+	fmt.Println("[INF] Schema drift:", len(report.Drifts), "distinct drifts in", report.Checked, "responses")
+	if err := s.drift.WriteReport(s.opts.DriftFile); err != nil {
+		fmt.Println("[Err] Could not write the drift report", err)
+	}
+}
+
+// claim reports whether id has not been fetched yet this sweep and marks it
+// as fetched.
+func (s *sweeper) claim(id string) bool {
+	s.fetchedMu.Lock()
+	defer s.fetchedMu.Unlock()
+	if s.fetched[id] {
+		return false
+	}
+	s.fetched[id] = true
+	return true
+}
+
+func (s *sweeper) addToSnapshot(flight model.Flight) {
+	s.snapshotMu.Lock()
+	defer s.snapshotMu.Unlock()
+	s.snapshot = append(s.snapshot, flight)
+}
+
+// takeSnapshot returns the positions of the finished sweep, each flight
+// once even when overlapping tiles reported it twice.
+func (s *sweeper) takeSnapshot() []model.Flight {
+	s.snapshotMu.Lock()
+	defer s.snapshotMu.Unlock()
+	seen := make(map[string]bool, len(s.snapshot))
+	flights := make([]model.Flight, 0, len(s.snapshot))
+	for _, f := range s.snapshot {
+		if !seen[f.ID] {
+			seen[f.ID] = true
+			flights = append(flights, f)
+		}
+	}
+	s.snapshot = nil
+	return flights
+}
+
+// movementEvents reports movements and closes the arrival estimates of the
+// flights that landed.
+func (s *sweeper) movementEvents(events []movement.Event) {
+	for _, event := range events {
+		fmt.Println("[INF] Movement", event)
+		if s.opts.OnMovement != nil {
+			s.opts.OnMovement(event)
+		}
+		if event.Type != movement.Arrival {
+			continue
+		}
+		if outcome := s.etas.Arrive(event.FlightID, event.Time, event.Airport); outcome != nil {
+			fmt.Println("[INF] ETA", outcome)
+			if s.opts.OnEta != nil {
+				s.opts.OnEta(*outcome)
+			}
+		}
+	}
+}
+
+// destination is f's destination with its coordinates, which feed entries
+// leave out, looked up in Options.Airports.
+func (s *sweeper) destination(f *model.Flight) *model.Airport {
+	d := f.Destination
+	if d == nil || d.Lat != 0 || d.Lon != 0 || s.opts.Airports == nil {
+		return d
+	}
+	if a, ok := s.opts.Airports.Airport(d.Code()); ok {
+		return a
+	}
+	return d
+}
+
+func (s *sweeper) geofenceEvents(events []geofence.Event) {
+	for _, event := range events {
+		fmt.Println("[INF] Geofence", event)
+		if s.opts.OnGeofence != nil {
+			s.opts.OnGeofence(event)
+		}
 	}
-	// client := new(http.Client(tr))
-	//disable certificate check
-	// b := fmt.Sprintf("%s,%s,%s,%s", flightBounds.Bounds[0].TLX, flightBounds[0].TLY, flightBounds[0].BRX, flightBounds[0].BRY)
-	// params := url.Values{}
-	// params.Add("bounds",b)
 }
 
-func getFlights(bound Bound, client tls_client.HttpClient, rdb *redis.Client, wg *sync.WaitGroup, sem chan struct{}) {
+func (s *sweeper) getFlights(bound Bound, wg *sync.WaitGroup, sem chan struct{}) {
 	defer wg.Done()
 	sem <- struct{}{}        // Acquire a token
 	defer func() { <-sem }() // Release the token when done
-	//Http client:
-	//note: using tls-client package to imporsonate TLS fingerprinting to bypass cloudflare's restrictions
 
-	realTimeFlightDataURl := "https://data-cloud.flightradar24.com/zones/fcgi/feed.js"
 	//uri := fmt.Sprintf("%.2f,%.2f,%.2f,%.2f", bound.TLY, bound.BRY, bound.TLX, bound.BRX)
 	uri := url.QueryEscape(fmt.Sprintf("%.2f,%.2f,%.2f,%.2f", bound.TLY, bound.BRY, bound.TLX, bound.BRX))
 	//uri := url.QueryEscape("74.0,70.0,20.0,28.0")
-	reqURL := fmt.Sprintf("%s?bounds=%s", realTimeFlightDataURl, uri)
-	req, err := http.NewRequest("GET", reqURL, nil)
-	//TODO: change this to a setter so you set the headers only once
-	req.Header.Set("accept-encoding", "gzip, br")
-	req.Header.Set("accept-language", "pt-BR,pt;q=0.9,en-US;q=0.8,en;q=0.7")
-	req.Header.Set("cache-control", "max-age=0")
-	req.Header.Set("origin", "https://www.flightradar24.com")
-	req.Header.Set("referer", "https://www.flightradar24.com/")
-	req.Header.Set("sec-fetch-dest", "empty")
-	req.Header.Set("sec-fetch-mode", "cors")
-	req.Header.Set("sec-fetch-site", "same-site")
-	req.Header.Set("user-agent", "Mozilla/5.0 (Windows NT 6.1) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/87.0.4280.88 Safari/537.36")
-
-	res, err := client.Do(req)
+	reqURL := fmt.Sprintf("%s?bounds=%s", feedURL, uri)
 
+	body, err := s.client.get(context.Background(), reqURL)
 	if err != nil {
 		fmt.Println("There was an error sending your request", err)
 		return
 	}
-	defer res.Body.Close()
 
-	fmt.Println(res.StatusCode)
-	body, err := ioutil.ReadAll(res.Body)
+	s.drift.CheckFeed(body)
+	feed, err := ParseFeed(body)
 	if err != nil {
-		fmt.Println("Error reading response body:", err)
-		return
-	}
-
-	var JsonResponse ApiStruct
-	if err = json.Unmarshal(body, &JsonResponse); err != nil {
 		fmt.Println("THere was an error encoding the json", err)
 		return
 	}
 
-	JsonResponse.Data = make(map[string]interface{})
-	if err := json.Unmarshal(body, &JsonResponse.Data); err != nil {
-		fmt.Println("Error:", err)
-		return
+	// Every entry extends its flight's trail. Flights that just started
+	// squawking an alert code go first and are fetched even if an earlier
+	// sweep already did, though still once per sweep.
+	var alerted []string
+	for i := range feed.Flights {
+		if err := s.trails.addFeed(&feed.Flights[i]); err != nil {
+			fmt.Println("[Err] Could not store the trail", err)
+		}
+		flight := feed.Flights[i].Canonical()
+		if s.opts.Reference != nil {
+			s.opts.Reference.EnrichFlight(&flight)
+		}
+		s.addToSnapshot(flight)
+		s.movementEvents(s.movements.Feed(&flight))
+		s.etas.Observe(&flight, s.destination(&flight))
+		if s.fences.Len() > 0 {
+			s.geofenceEvents(s.fences.Update(&flight))
+		}
+		if alert := s.squawks.check(&feed.Flights[i]); alert != nil {
+			fmt.Println("[ALERT]", alert)
+			if s.opts.OnAlert != nil {
+				s.opts.OnAlert(*alert)
+			}
+			alerted = append(alerted, alert.Flight.ID)
+			if s.claim(alert.Flight.ID) {
+				s.getFlightDetail(alert.Flight.ID)
+			}
+		}
 	}
 
-	outerloop:
-	for key, _ := range JsonResponse.Data {
-		if key == "version" || key == "full_count" {
+outerloop:
+	for _, flight := range feed.Flights {
+		key := flight.ID
+		if slices.Contains(alerted, key) {
+			flightIDs = append(flightIDs, key)
 			continue
 		}
 		for _, f := range Temp {
 			if key == f {
-				//update trail
 				fmt.Println("found same id", key)
 				flightIDs = append(flightIDs, key)
 				continue outerloop
 			}
 		}
-		getFlightDetail(key, client, rdb)
+		if s.claim(key) {
+			s.getFlightDetail(key)
+		}
 		flightIDs = append(flightIDs, key)
 	}
 	fmt.Println(flightIDs)
 }
 
-func getFlightDetail(flightNumber string, client tls_client.HttpClient, rdb *redis.Client) {
+func (s *sweeper) getFlightDetail(flightNumber string) {
 	ctx := context.Background()
-	url := fmt.Sprintf("https://data-live.flightradar24.com/clickhandler/?flight=%s", flightNumber)
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err!=nil{fmt.Println("[Err] error initializing the reques", err)}
+	body, err := s.client.fetchFlightDetail(ctx, flightNumber)
+	if err != nil {
+		fmt.Println("There was an error sending your request", err)
+		return
+	}
 
-	for retry:=0;retry<20;retry ++{
-		res, err := client.Do(req)
-		if err != nil {
-			fmt.Println("There was an error sending your request", err)
-			return
-		}
-		if res.StatusCode != 200 {
-			fmt.Println("[INF] Retrying...", retry)
-			time.Sleep(3 * time.Second)
-			continue
-		}
-		defer res.Body.Close()
-		body, err := ioutil.ReadAll(res.Body)
-		if err != nil {
-			fmt.Println("Error reading response body:", err)
-			return
-		}
-		
-		
-		// Set the JSON record in Redis
-		err = rdb.Set(ctx, "Flight:"+flightNumber, body, 0).Err()
-		if err != nil {
-			fmt.Println("Error setting JSON record: %v", err)
-			return
-		}
+	// Set the JSON record in Redis
+	err = s.rdb.Set(ctx, "Flight:"+flightNumber, body, 0).Err()
+	if err != nil {
+		fmt.Println("Error setting JSON record:", err)
+		return
+	}
 
-		
-		var JsonResponse FlightDetails
-		if err = json.Unmarshal(body, &JsonResponse); err != nil {
-			fmt.Println("[Err] There was an error encoding the json", err)
-			return
-		}
-		
-		// flightid
-		// marshalled, err := json.Marshal(JsonResponse)
-		currentDir, err := os.Getwd()
-		flightRegNum := JsonResponse.Aircraft.Registration
-		flightDir := path.Join(currentDir, "Data", flightRegNum)
-		if _, e := os.Stat(flightDir); os.IsNotExist(e) {
-			os.Mkdir(flightDir, 0777)
+	s.drift.CheckDetails(body)
+	var JsonResponse FlightDetails
+	if err = json.Unmarshal(body, &JsonResponse); err != nil {
+		fmt.Println("[Err] There was an error encoding the json", err)
+		return
+	}
+	if s.opts.OnDetail != nil {
+		s.opts.OnDetail(&JsonResponse)
+	}
+
+	flight := JsonResponse.Canonical()
+	if len(s.opts.Classes) > 0 && !slices.Contains(s.opts.Classes, flight.Class) {
+		return
+	}
+
+	// The record carries every point seen so far, not just the ones FR24
+	// still returns; new points are also appended to its trail file. Once
+	// stored, a flight keeps its path even if the fields it was built from
+	// change.
+	file := s.trails.recordFile(flightNumber)
+	if file == "" {
+		file = path.Join(s.dataDir, s.layout.path(&JsonResponse, s.trails.firstSeen(flightNumber)))
+	}
+	trail, err := s.trails.addDetails(flightNumber, file, &JsonResponse)
+	if err != nil {
+		fmt.Println("[Err] Could not store the trail", err)
+	}
+	JsonResponse.Trail = trail
+	s.writeRecord(file, &JsonResponse)
+}
+
+// refresh runs the go-around and holding detectors over the accumulated
+// trail of every stored flight that moved this sweep, and rewrites the
+// records that gained an event or whose flight just landed, so their stats
+// cover the whole flight. Details are only fetched once per flight, so this
+// is where later parts of a flight are seen.
+func (s *sweeper) refresh() {
+	for _, f := range s.trails.updated() {
+		merged := f.details.Canonical()
+		_, fresh := s.events.Observe(&merged)
+		s.detected(fresh)
+		if len(fresh) > 0 || (!f.landed && stats.Landed(&merged)) {
+			s.writeRecord(f.file, &f.details)
 		}
+	}
+}
 
-		output, err := json.Marshal(JsonResponse)
-		if len(JsonResponse.FlightHistory.Aircraft) == 0{
-			fmt.Println("[Err] There was an Error Writing Into file")
-			return
+func (s *sweeper) detected(events []detect.Event) {
+	for _, event := range events {
+		fmt.Println("[INF] Detected", event)
+		if s.opts.OnEvent != nil {
+			s.opts.OnEvent(event)
 		}
+	}
+}
 
-		file, err := os.Create(path.Join(flightDir, strconv.Itoa(JsonResponse.FlightHistory.Aircraft[0].Time.Real.Departure)+".json"))
-		if err!=nil{fmt.Println("There was an error writing in file");return}
-		defer file.Close()
+// writeRecord derives the record of details, whose trail is the flight's
+// accumulated one, and stores it in file.
+func (s *sweeper) writeRecord(file string, details *FlightDetails) {
+	registry := s.registry.Check(details.Canonical().Aircraft)
+	// Enriching first also fills the airline name into the stored record.
+	var enrichment *Enrichment
+	if s.opts.Reference != nil {
+		e := s.opts.Reference.EnrichDetails(details)
+		enrichment = &e
+	}
+	record := NewRecord(details)
+	record.Registry = registry
+	record.Reference = enrichment
+	merged := details.Canonical()
+	if stats.Landed(&merged) {
+		s.trails.setLanded(merged.ID)
+	}
+	if estimate, ok := s.etas.Observe(&merged, s.destination(&merged)); ok {
+		record.Eta = estimate
+	}
+	movements, newMovements := s.movements.Trail(&merged)
+	record.Movements = movements
+	s.movementEvents(newMovements)
+	all, fresh := s.events.Observe(&merged)
+	record.Events = all
+	s.detected(fresh)
+	record.Trail = reduceTrail(record.Trail, s.opts.Resample, s.opts.Simplify)
 
-		_, err = file.Write(output)
-		if err != nil {
-			fmt.Println("[Err] There was an Error Writing Into file")
-			return
-		}
+	output, err := json.Marshal(record)
+	if err != nil {
+		fmt.Println("[Err] There was an error encoding the record", err)
 		return
 	}
+	if err := writeFile(file, output); err != nil {
+		fmt.Println("[Err] There was an Error Writing Into file", err)
+	}
+}
 
-	
-
+// writeFile creates the parent directories and replaces file atomically so
+// readers never see a half written record. Each write gets its own
+// temporary file, since an alert fetch and overlapping tiles can write the
+// same flight at once.
+func writeFile(file string, data []byte) error {
+	if err := os.MkdirAll(path.Dir(file), 0777); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(path.Dir(file), path.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
 }
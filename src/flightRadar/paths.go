@@ -0,0 +1,133 @@
+package flightRadar
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultPathTemplate stores every flight once per aircraft and UTC day.
+// Repeated fetches of the same flight overwrite its file.
+const DefaultPathTemplate = "{registration}/{date}/{flight_id}.json"
+
+// pathFields are the placeholders a path template may use. start is when
+// the flight began, see flightStart.
+var pathFields = map[string]func(d *FlightDetails, start time.Time) string{
+	"registration": func(d *FlightDetails, _ time.Time) string {
+		if d.Aircraft.Registration == "" && d.Aircraft.Hex != "" {
+			return "hex-" + strings.ToLower(d.Aircraft.Hex)
+		}
+		return d.Aircraft.Registration
+	},
+	"hex":       func(d *FlightDetails, _ time.Time) string { return strings.ToLower(d.Aircraft.Hex) },
+	"flight_id": func(d *FlightDetails, _ time.Time) string { return d.Identification.ID },
+	"callsign": func(d *FlightDetails, _ time.Time) string {
+		if d.Identification.Callsign == "" {
+			return d.Identification.Number.Default
+		}
+		return d.Identification.Callsign
+	},
+	"date": func(d *FlightDetails, start time.Time) string {
+		if start.IsZero() {
+			return ""
+		}
+		return start.Format("2006-01-02")
+	},
+	"departure": func(d *FlightDetails, start time.Time) string {
+		if start.IsZero() {
+			return ""
+		}
+		return fmt.Sprint(start.Unix())
+	},
+}
+
+var placeholder = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// PathTemplate lays stored records out under the Data directory.
+type PathTemplate struct {
+	template string
+	unique   bool
+}
+
+// ParsePathTemplate checks that template only uses known placeholders:
+// {registration}, {hex}, {flight_id}, {callsign}, {date} (UTC, YYYY-MM-DD)
+// and {departure} (epoch seconds). Templates without {flight_id} get the
+// flight ID appended to the file name so two flights never share a file.
+func ParsePathTemplate(template string) (PathTemplate, error) {
+	if template == "" {
+		template = DefaultPathTemplate
+	}
+	for _, m := range placeholder.FindAllStringSubmatch(template, -1) {
+		if _, ok := pathFields[m[1]]; !ok {
+			return PathTemplate{}, fmt.Errorf("unknown path placeholder {%s}", m[1])
+		}
+	}
+	if path.IsAbs(template) || strings.Contains(template, "..") {
+		return PathTemplate{}, fmt.Errorf("path template %q must stay inside the Data directory", template)
+	}
+	return PathTemplate{template: template, unique: strings.Contains(template, "{flight_id}")}, nil
+}
+
+// Path returns the slash separated path of a record relative to Data.
+// Missing fields fall back to "unknown" rather than collapsing a directory.
+func (t PathTemplate) Path(d *FlightDetails) string {
+	return t.path(d, time.Time{})
+}
+
+// path is Path with the time the flight was first seen in the feed, which
+// dates flights whose record carries no departure or trail.
+func (t PathTemplate) path(d *FlightDetails, seen time.Time) string {
+	start := flightStart(d, seen)
+	template := t.template
+	if template == "" {
+		template = DefaultPathTemplate
+		t.unique = true
+	}
+	p := placeholder.ReplaceAllStringFunc(template, func(m string) string {
+		value := pathFields[m[1:len(m)-1]](d, start)
+		return sanitizePathPart(value)
+	})
+	if !t.unique {
+		ext := path.Ext(p)
+		p = strings.TrimSuffix(p, ext) + "_" + sanitizePathPart(d.Identification.ID) + ext
+	}
+	return path.Clean(p)
+}
+
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func sanitizePathPart(s string) string {
+	s = unsafePathChars.ReplaceAllString(strings.TrimSpace(s), "_")
+	s = strings.Trim(s, "._")
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+// flightStart is when the flight began: FR24's first timestamp, else the
+// earliest position seen in the trail or the feed, else the scheduled
+// departure. It only uses what stays the same between fetches, so a
+// flight's files stay in one place; the real departure is left out as it is
+// null until takeoff. With none of these it is zero and the date
+// placeholders are "unknown".
+func flightStart(d *FlightDetails, seen time.Time) time.Time {
+	if d.FirstTimestamp > 0 {
+		return time.Unix(int64(d.FirstTimestamp), 0).UTC()
+	}
+	first := seen
+	for _, p := range d.Trail {
+		if t := time.Unix(p.Ts, 0); p.Ts > 0 && (first.IsZero() || t.Before(first)) {
+			first = t
+		}
+	}
+	if !first.IsZero() {
+		return first.UTC()
+	}
+	if t := d.Time.Scheduled.DepartureTime(); t != nil {
+		return *t
+	}
+	return time.Time{}
+}
@@ -0,0 +1,73 @@
+package flightRadar
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePathTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		ok       bool
+	}{
+		{"", true},
+		{DefaultPathTemplate, true},
+		{"{hex}/{callsign}_{departure}.json", true},
+		{"{date}/{registration}.json", true},
+		{"{tail}/{flight_id}.json", false},
+		{"/abs/{flight_id}.json", false},
+		{"../{flight_id}.json", false},
+	}
+	for _, tt := range tests {
+		_, err := ParsePathTemplate(tt.template)
+		if (err == nil) != tt.ok {
+			t.Errorf("%q: error %v, want ok %v", tt.template, err, tt.ok)
+		}
+	}
+}
+
+func TestPathTemplate(t *testing.T) {
+	seen := time.Date(2024, 5, 1, 23, 50, 0, 0, time.UTC)
+	departed := seen.Add(20 * time.Minute).Unix()
+	scheduled := seen.Add(-time.Hour).Unix()
+
+	details := func(first int, trail []int64, departure *int64) *FlightDetails {
+		d := &FlightDetails{FirstTimestamp: first}
+		d.Identification.ID = "35a1b2c3"
+		d.Identification.Callsign = "DLH4AB"
+		d.Aircraft.Registration = "D-AIBL"
+		d.Aircraft.Hex = "3C6589"
+		d.Time.Scheduled.Departure = &scheduled
+		d.Time.Real.Departure = departure
+		for _, ts := range trail {
+			d.Trail = append(d.Trail, TrailPoint{Ts: ts})
+		}
+		return d
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		details  *FlightDetails
+		seen     time.Time
+		want     string
+	}{
+		{"before departure", "", details(0, nil, nil), seen, "D-AIBL/2024-05-01/35a1b2c3.json"},
+		{"after departure", "", details(0, nil, &departed), seen, "D-AIBL/2024-05-01/35a1b2c3.json"},
+		{"after departure with a trail", "{departure}.json", details(0, []int64{departed + 600, seen.Unix()}, &departed), seen,
+			"1714607400_35a1b2c3.json"},
+		{"first timestamp", "{date}/{departure}/{flight_id}.json", details(int(seen.Unix())-60, nil, &departed), time.Time{},
+			"2024-05-01/1714607340/35a1b2c3.json"},
+		{"only scheduled", "{date}/{callsign}.json", details(0, nil, nil), time.Time{}, "2024-05-01/DLH4AB_35a1b2c3.json"},
+		{"nothing known", "{date}/{hex}/{flight_id}.json", &FlightDetails{}, time.Time{}, "unknown/unknown/unknown.json"},
+	}
+	for _, tt := range tests {
+		layout, err := ParsePathTemplate(tt.template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := layout.path(tt.details, tt.seen); got != tt.want {
+			t.Errorf("%s: path %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package flightRadar
+
+import (
+	"radar/classify"
+	"radar/detect"
+	"radar/eta"
+	"radar/icao"
+	"radar/model"
+	"radar/movement"
+	"radar/phase"
+	"radar/stats"
+)
+
+// Record is what the sweep stores for a clickhandler fetch: the FR24 record
+// as received plus fields derived from it. The derived keys do not clash
+// with FR24's, so a stored record still decodes into FlightDetails.
+type Record struct {
+	FlightDetails
+	Delays     model.Delays     `json:"delays"`
+	LocalTimes model.LocalTimes `json:"local_times"`
+	// Registry is what the ICAO address says about the aircraft, including
+	// where it disagrees with the reported registration and country.
+	Registry *icao.Result `json:"registry,omitempty"`
+	// Classification is the flight's class and the evidence for it.
+	Classification classify.Result `json:"classification"`
+	Stats          stats.Stats     `json:"stats"`
+	// Phases are the phase transitions inferred from the trail, with the
+	// takeoff and landing times they imply.
+	Phases phase.Result `json:"phases"`
+	// Events are the go-arounds and holds found in the trail.
+	Events []detect.Event `json:"events,omitempty"`
+	// Movements are the departures and arrivals inferred from the trail.
+	Movements []movement.Event `json:"movements,omitempty"`
+	// Reference is what the airport and airline directory knows about the
+	// flight's airports and airline.
+	Reference *Enrichment `json:"reference,omitempty"`
+	// Eta is our own arrival estimate for airborne flights, see package eta.
+	Eta *eta.Estimate `json:"estimated_arrival,omitempty"`
+}
+
+// Enrichment is the reference data resolved for one flight, see
+// reference.Directory.
+type Enrichment struct {
+	OriginAirport      *model.Airport  `json:"origin_airport,omitempty"`
+	DestinationAirport *model.Airport  `json:"destination_airport,omitempty"`
+	Airline            *model.Operator `json:"airline,omitempty"`
+}
+
+func NewRecord(details *FlightDetails) *Record {
+	flight := details.Canonical()
+	record := &Record{
+		FlightDetails:  *details,
+		Delays:         flight.Delays,
+		LocalTimes:     flight.LocalTimes(),
+		Classification: classify.Flight(&flight),
+		Stats:          stats.Flight(&flight),
+		Phases:         phase.Flight(&flight),
+	}
+	// phase.Flight labelled the canonical trail, which runs oldest first;
+	// the stored trail is newest first and is copied so details keeps its
+	// own.
+	n := len(flight.Trail)
+	record.Trail = make([]TrailPoint, n)
+	for i, p := range flight.Trail {
+		record.Trail[n-1-i] = details.Trail[n-1-i]
+		record.Trail[n-1-i].Phase = p.Phase
+	}
+	return record
+}
@@ -0,0 +1,214 @@
+package flightRadar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+const searchURL = "https://www.flightradar24.com/v1/search/web/find"
+
+// SearchLimit caps how many hits FR24 returns for a single query.
+var SearchLimit = 50
+
+// searchResponse mirrors the JSON returned by FR24's search endpoint. Hits
+// are decoded one by one so a malformed hit does not fail the others.
+type searchResponse struct {
+	Results []json.RawMessage `json:"results"`
+}
+
+type searchHit struct {
+	ID     string          `json:"id"`
+	Label  string          `json:"label"`
+	Detail json.RawMessage `json:"detail"`
+	Type   string          `json:"type"`
+	Match  string          `json:"match"`
+	Name   string          `json:"name"`
+}
+
+// LiveFlightHit is a flight currently tracked by FR24. FlightID can be passed
+// straight to Client.FlightDetail.
+type LiveFlightHit struct {
+	FlightID     string  `json:"flight_id"`
+	Label        string  `json:"label"`
+	Callsign     string  `json:"callsign"`
+	Flight       string  `json:"flight"`
+	Registration string  `json:"registration"`
+	AircraftType string  `json:"aircraft_type"`
+	Operator     string  `json:"operator"`
+	Route        string  `json:"route"`
+	Origin       string  `json:"origin"`
+	Destination  string  `json:"destination"`
+	Lat          float64 `json:"lat"`
+	Lon          float64 `json:"lon"`
+}
+
+type AircraftHit struct {
+	Registration string `json:"registration"`
+	Label        string `json:"label"`
+	Model        string `json:"model"`
+	Hex          string `json:"hex"`
+	OperatorIata string `json:"operator_iata"`
+	OperatorIcao string `json:"operator_icao"`
+}
+
+type AirportHit struct {
+	Iata  string  `json:"iata"`
+	Label string  `json:"label"`
+	Lat   float64 `json:"lat"`
+	Lon   float64 `json:"lon"`
+	Size  int     `json:"size"`
+}
+
+type AirlineHit struct {
+	Icao  string `json:"icao"`
+	Iata  string `json:"iata"`
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	ID    int    `json:"id"`
+}
+
+// SearchResults groups the hits of a query by kind. Scheduled flights that
+// are not airborne are not returned since they have no flight ID yet.
+type SearchResults struct {
+	Query    string          `json:"query"`
+	Live     []LiveFlightHit `json:"live"`
+	Aircraft []AircraftHit   `json:"aircraft"`
+	Airports []AirportHit    `json:"airports"`
+	Airlines []AirlineHit    `json:"airlines"`
+}
+
+// FlightID returns the first live flight matching the query.
+func (r *SearchResults) FlightID() (string, bool) {
+	if len(r.Live) == 0 {
+		return "", false
+	}
+	return r.Live[0].FlightID, true
+}
+
+// Search resolves a callsign, flight number, registration, airport or airline
+// through FR24's search endpoint.
+func (c *Client) Search(ctx context.Context, query string) (*SearchResults, error) {
+	reqURL := fmt.Sprintf("%s?query=%s&limit=%d", searchURL, url.QueryEscape(query), SearchLimit)
+	body, err := c.get(ctx, reqURL)
+	if err != nil {
+		return nil, err
+	}
+	return parseSearch(query, body)
+}
+
+func parseSearch(query string, body []byte) (*SearchResults, error) {
+	var res searchResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+
+	results := &SearchResults{Query: query}
+	for _, raw := range res.Results {
+		var hit searchHit
+		err := json.Unmarshal(raw, &hit)
+		if err == nil {
+			err = results.add(hit)
+		}
+		if err != nil {
+			fmt.Println("[Err] Skipping search hit", string(raw), err)
+		}
+	}
+	return results, nil
+}
+
+// add decodes the detail of a hit into the list of its kind. Kinds other
+// than live flights, aircraft, airports and airlines are ignored.
+func (r *SearchResults) add(hit searchHit) error {
+	switch hit.Type {
+	case "live":
+		var d struct {
+			Lat      float64 `json:"lat"`
+			Lon      float64 `json:"lon"`
+			Reg      string  `json:"reg"`
+			AcType   string  `json:"ac_type"`
+			Callsign string  `json:"callsign"`
+			Flight   string  `json:"flight"`
+			Operator string  `json:"operator"`
+			Route    string  `json:"route"`
+			SchdFrom string  `json:"schd_from"`
+			SchdTo   string  `json:"schd_to"`
+		}
+		if err := decodeDetail(hit.Detail, &d); err != nil {
+			return err
+		}
+		r.Live = append(r.Live, LiveFlightHit{
+			FlightID:     hit.ID,
+			Label:        hit.Label,
+			Callsign:     d.Callsign,
+			Flight:       d.Flight,
+			Registration: d.Reg,
+			AircraftType: d.AcType,
+			Operator:     d.Operator,
+			Route:        d.Route,
+			Origin:       d.SchdFrom,
+			Destination:  d.SchdTo,
+			Lat:          d.Lat,
+			Lon:          d.Lon,
+		})
+	case "aircraft":
+		var d struct {
+			Equip     string `json:"equip"`
+			Hex       string `json:"hex"`
+			OwnerIata string `json:"owner_iata"`
+			OwnerIcao string `json:"owner_icao"`
+		}
+		if err := decodeDetail(hit.Detail, &d); err != nil {
+			return err
+		}
+		r.Aircraft = append(r.Aircraft, AircraftHit{
+			Registration: hit.ID,
+			Label:        hit.Label,
+			Model:        d.Equip,
+			Hex:          d.Hex,
+			OperatorIata: d.OwnerIata,
+			OperatorIcao: d.OwnerIcao,
+		})
+	case "airport":
+		var d struct {
+			Lat  float64 `json:"lat"`
+			Lon  float64 `json:"lon"`
+			Size int     `json:"size"`
+		}
+		if err := decodeDetail(hit.Detail, &d); err != nil {
+			return err
+		}
+		r.Airports = append(r.Airports, AirportHit{
+			Iata:  hit.ID,
+			Label: hit.Label,
+			Lat:   d.Lat,
+			Lon:   d.Lon,
+			Size:  d.Size,
+		})
+	case "operator":
+		var d struct {
+			OperatorID int    `json:"operator_id"`
+			Iata       string `json:"iata"`
+		}
+		if err := decodeDetail(hit.Detail, &d); err != nil {
+			return err
+		}
+		r.Airlines = append(r.Airlines, AirlineHit{
+			Icao:  hit.ID,
+			Iata:  d.Iata,
+			Name:  hit.Name,
+			Label: hit.Label,
+			ID:    d.OperatorID,
+		})
+	}
+	return nil
+}
+
+// decodeDetail tolerates the empty array FR24 sends instead of an object.
+func decodeDetail(raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 || raw[0] != '{' {
+		return nil
+	}
+	return json.Unmarshal(raw, v)
+}
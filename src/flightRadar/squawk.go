@@ -0,0 +1,135 @@
+package flightRadar
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"radar/model"
+)
+
+// EmergencySquawks are the codes every sweep alerts on.
+var EmergencySquawks = map[string]string{
+	"7500": "hijack",
+	"7600": "radio failure",
+	"7700": "emergency",
+}
+
+// Alert is emitted the first time a flight is seen squawking one of the
+// alert codes.
+type Alert struct {
+	Time    time.Time    `json:"time"`
+	Squawk  string       `json:"squawk"`
+	Meaning string       `json:"meaning"`
+	Flight  model.Flight `json:"flight"`
+}
+
+func (a Alert) String() string {
+	s := fmt.Sprintf("squawk %s (%s) %s %s %s", a.Squawk, a.Meaning,
+		a.Flight.Callsign, a.Flight.Aircraft.Registration, a.Flight.Aircraft.Hex)
+	if p := a.Flight.Position; p != nil {
+		s += fmt.Sprintf(" at %.4f,%.4f %dft", p.Lat, p.Lon, p.Altitude)
+	}
+	return s
+}
+
+// ParseSquawks reads extra alert codes as "code=meaning,code", e.g.
+// "7400=lost link,0020". Codes without a meaning are labelled "custom".
+func ParseSquawks(list string) (map[string]string, error) {
+	codes := make(map[string]string)
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		code, meaning, _ := strings.Cut(entry, "=")
+		code = strings.TrimSpace(code)
+		if len(code) != 4 || strings.Trim(code, "01234567") != "" {
+			return nil, fmt.Errorf("invalid squawk %q, want four octal digits", code)
+		}
+		if meaning = strings.TrimSpace(meaning); meaning == "" {
+			meaning = "custom"
+		}
+		codes[code] = meaning
+	}
+	return codes, nil
+}
+
+// squawkWatch remembers which flights have already been alerted on so a
+// flight only alerts again after changing its squawk.
+type squawkWatch struct {
+	codes map[string]string
+
+	mu      sync.Mutex
+	alerted map[string]string
+	seen    map[string]bool
+}
+
+func newSquawkWatch(extra map[string]string) *squawkWatch {
+	codes := make(map[string]string)
+	for code, meaning := range EmergencySquawks {
+		codes[code] = meaning
+	}
+	for code, meaning := range extra {
+		codes[code] = meaning
+	}
+	return &squawkWatch{codes: codes, alerted: make(map[string]string), seen: make(map[string]bool)}
+}
+
+// check returns an alert when flight has just started squawking an alert
+// code.
+func (w *squawkWatch) check(flight *FeedFlight) *Alert {
+	meaning, emergency := w.codes[flight.Squawk]
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !emergency {
+		delete(w.alerted, flight.ID)
+		return nil
+	}
+	w.seen[flight.ID] = true
+	if w.alerted[flight.ID] == flight.Squawk {
+		return nil
+	}
+	w.alerted[flight.ID] = flight.Squawk
+	return &Alert{
+		Time:    time.Now().UTC(),
+		Squawk:  flight.Squawk,
+		Meaning: meaning,
+		Flight:  flight.Canonical(),
+	}
+}
+
+// sweep forgets flights that were not seen squawking during the last sweep.
+func (w *squawkWatch) sweep() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for id := range w.alerted {
+		if !w.seen[id] {
+			delete(w.alerted, id)
+		}
+	}
+	w.seen = make(map[string]bool)
+}
+
+// AppendJSON adds v to file as one JSON line, e.g. an Alert or an event.
+func AppendJSON(file string, v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Dir(file), 0777); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
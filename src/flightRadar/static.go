@@ -0,0 +1,59 @@
+package flightRadar
+
+import (
+	"context"
+	"encoding/json"
+)
+
+const (
+	airportsURL = "https://www.flightradar24.com/_json/airports.php"
+	airlinesURL = "https://www.flightradar24.com/_json/airlines.php"
+)
+
+// StaticAirport is an entry of FR24's static airport list.
+type StaticAirport struct {
+	Name    string  `json:"name"`
+	Iata    string  `json:"iata"`
+	Icao    string  `json:"icao"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+	Country string  `json:"country"`
+	Alt     int     `json:"alt"`
+}
+
+// StaticAirline is an entry of FR24's static airline list.
+type StaticAirline struct {
+	Name string `json:"Name"`
+	Code string `json:"Code"`
+	Icao string `json:"ICAO"`
+}
+
+// AirportList fetches every airport FR24 knows about.
+func (c *Client) AirportList(ctx context.Context) ([]StaticAirport, error) {
+	body, err := c.get(ctx, airportsURL)
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Rows []StaticAirport `json:"rows"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, err
+	}
+	return list.Rows, nil
+}
+
+// AirlineList fetches every airline FR24 knows about.
+func (c *Client) AirlineList(ctx context.Context) ([]StaticAirline, error) {
+	body, err := c.get(ctx, airlinesURL)
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Rows []StaticAirline `json:"rows"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, err
+	}
+	return list.Rows, nil
+}
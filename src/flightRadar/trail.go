@@ -0,0 +1,302 @@
+package flightRadar
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"radar/model"
+	"radar/track"
+)
+
+// trailExpiry is how long a flight's trail is kept in memory after it was
+// last seen.
+const trailExpiry = time.Hour
+
+// TrailFile is where the accumulated trail of the record stored at file is
+// kept: the record's path with a .trail.jsonl extension.
+func TrailFile(file string) string {
+	return strings.TrimSuffix(file, path.Ext(file)) + ".trail.jsonl"
+}
+
+// Point is the feed entry as a trail point.
+func (f *FeedFlight) Point() TrailPoint {
+	return TrailPoint{Lat: f.Lat, Lng: f.Lon, Alt: f.Altitude, Spd: f.Speed, Ts: f.Timestamp, Hd: f.Track}
+}
+
+// trailPoint converts a canonical position back into FR24's trail format.
+func trailPoint(p model.Position) TrailPoint {
+	return TrailPoint{Lat: p.Lat, Lng: p.Lon, Alt: p.Altitude, Spd: p.GroundSpeed, Ts: p.Time.Unix(), Hd: p.Track, Phase: p.Phase}
+}
+
+// reduceTrail resamples and then simplifies a trail that is newest first,
+// as FR24 orders it.
+func reduceTrail(trail []TrailPoint, interval time.Duration, tol track.Tolerance) []TrailPoint {
+	if interval <= 0 && tol.IsZero() {
+		return trail
+	}
+	positions := make([]model.Position, len(trail))
+	for i, p := range trail {
+		positions[len(trail)-1-i] = p.Canonical()
+	}
+	positions = track.Simplify(track.Resample(positions, interval), tol)
+	reduced := make([]TrailPoint, len(positions))
+	for i, p := range positions {
+		reduced[len(positions)-1-i] = trailPoint(p)
+	}
+	return reduced
+}
+
+// trails accumulates every flight's positions across sweeps from both feed
+// entries and clickhandler trails. Points are deduplicated by timestamp and
+// only new ones are appended to the flight's trail file, one JSON point per
+// line in the order they were learned.
+type trails struct {
+	mu      sync.Mutex
+	flights map[string]*trail
+}
+
+type trail struct {
+	// file is empty until the flight's details have been fetched; points
+	// are only kept in memory until then.
+	file    string
+	seen    map[int64]bool
+	points  []TrailPoint // oldest first
+	pending []TrailPoint // not written yet
+	updated time.Time
+	// record is where the flight's record is stored and details the
+	// clickhandler record it was built from, without its trail. Both are
+	// unset until the details have been fetched.
+	record  string
+	details *FlightDetails
+	// grown is set when points were added since the last refresh, landed
+	// once a record of the landed flight was written.
+	grown, landed bool
+}
+
+// stored is a flight whose record is kept on disk, with the accumulated
+// trail merged into its details newest first.
+type stored struct {
+	file    string
+	details FlightDetails
+	landed  bool
+}
+
+func newTrails() *trails {
+	return &trails{flights: make(map[string]*trail)}
+}
+
+func (t *trails) get(id string) *trail {
+	tr, ok := t.flights[id]
+	if !ok {
+		tr = &trail{seen: make(map[int64]bool)}
+		t.flights[id] = tr
+	}
+	tr.updated = time.Now()
+	return tr
+}
+
+// addFeed adds a feed position, writing it when the trail file is known.
+func (t *trails) addFeed(flight *FeedFlight) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tr := t.get(flight.ID)
+	tr.add([]TrailPoint{flight.Point()})
+	return tr.flush()
+}
+
+// addDetails merges the trail of a clickhandler record, stored in record,
+// into the flight's trail and returns the merged trail newest first as FR24
+// orders it. The record's trail file keeps the points.
+func (t *trails) addDetails(id, record string, details *FlightDetails) ([]TrailPoint, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tr := t.get(id)
+	d := *details
+	d.Trail = nil
+	tr.record, tr.details = record, &d
+	file := TrailFile(record)
+	var err error
+	if tr.file != file {
+		// First details of the flight: pick up what an earlier run
+		// already stored there and write everything else.
+		tr.file = file
+		var stored []TrailPoint
+		stored, err = ReadTrail(file)
+		inFile := make(map[int64]bool)
+		var fresh []TrailPoint
+		for _, p := range stored {
+			inFile[p.Ts] = true
+			if !tr.seen[p.Ts] {
+				tr.seen[p.Ts] = true
+				fresh = append(fresh, p)
+			}
+		}
+		tr.merge(fresh)
+		tr.pending = nil
+		for _, p := range tr.points {
+			if !inFile[p.Ts] {
+				tr.pending = append(tr.pending, p)
+			}
+		}
+	}
+	tr.add(details.Trail)
+	// The caller builds the record from the merged trail.
+	tr.grown = false
+	if ferr := tr.flush(); ferr != nil {
+		err = ferr
+	}
+	return tr.merged(), err
+}
+
+// updated returns the stored flights whose trail grew since the last call.
+func (t *trails) updated() []stored {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var flights []stored
+	for _, tr := range t.flights {
+		if !tr.grown || tr.details == nil {
+			continue
+		}
+		tr.grown = false
+		d := *tr.details
+		d.Trail = tr.merged()
+		flights = append(flights, stored{file: tr.record, details: d, landed: tr.landed})
+	}
+	return flights
+}
+
+// setLanded notes that the record of a landed flight was written.
+func (t *trails) setLanded(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if tr, ok := t.flights[id]; ok {
+		tr.landed = true
+	}
+}
+
+// recordFile is where the flight's record was first stored, empty before
+// its details were fetched.
+func (t *trails) recordFile(id string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if tr, ok := t.flights[id]; ok {
+		return tr.record
+	}
+	return ""
+}
+
+// firstSeen is the time of the earliest point of a flight's trail, zero
+// when none is known.
+func (t *trails) firstSeen(id string) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tr, ok := t.flights[id]
+	if !ok || len(tr.points) == 0 {
+		return time.Time{}
+	}
+	return time.Unix(tr.points[0].Ts, 0).UTC()
+}
+
+// expire forgets flights not seen for trailExpiry.
+func (t *trails) expire() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, tr := range t.flights {
+		if time.Since(tr.updated) > trailExpiry {
+			delete(t.flights, id)
+		}
+	}
+}
+
+func (tr *trail) add(points []TrailPoint) {
+	var fresh []TrailPoint
+	for _, p := range points {
+		if p.Ts <= 0 || tr.seen[p.Ts] {
+			continue
+		}
+		tr.seen[p.Ts] = true
+		fresh = append(fresh, p)
+	}
+	if len(fresh) > 0 {
+		tr.grown = true
+	}
+	tr.pending = append(tr.pending, fresh...)
+	tr.merge(fresh)
+}
+
+// merged returns the trail newest first.
+func (tr *trail) merged() []TrailPoint {
+	merged := make([]TrailPoint, len(tr.points))
+	for i, p := range tr.points {
+		merged[len(merged)-1-i] = p
+	}
+	return merged
+}
+
+func (tr *trail) merge(points []TrailPoint) {
+	tr.points = append(tr.points, points...)
+	sort.SliceStable(tr.points, func(i, j int) bool { return tr.points[i].Ts < tr.points[j].Ts })
+}
+
+func (tr *trail) flush() error {
+	if tr.file == "" || len(tr.pending) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(path.Dir(tr.file), 0777); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(tr.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, p := range tr.pending {
+		if err := enc.Encode(p); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	tr.pending = nil
+	return nil
+}
+
+// ReadTrail reads a trail file, oldest point first with duplicates
+// removed. A missing file is an empty trail. When a line cannot be decoded,
+// such as a half written last one, the points before it are returned with
+// the error.
+func ReadTrail(file string) ([]TrailPoint, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var points []TrailPoint
+	seen := make(map[int64]bool)
+	dec := json.NewDecoder(f)
+	for dec.More() {
+		var p TrailPoint
+		if err = dec.Decode(&p); err != nil {
+			break
+		}
+		if !seen[p.Ts] {
+			seen[p.Ts] = true
+			points = append(points, p)
+		}
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Ts < points[j].Ts })
+	return points, err
+}
@@ -0,0 +1,110 @@
+package flightRadar
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestTrails(t *testing.T) {
+	tests := []struct {
+		name    string
+		inFile  []int64 // written by an earlier run
+		before  []int64 // feed points before the details
+		details []int64 // clickhandler trail, newest first
+		after   []int64 // feed points after the details
+		merged  []int64 // newest first, as addDetails returns it
+		lines   []int64 // trail file lines, in the order they were learned
+	}{
+		{"details only", nil, nil, []int64{300, 200, 100}, nil,
+			[]int64{300, 200, 100}, []int64{300, 200, 100}},
+		{"feed before and after", nil, []int64{250, 350}, []int64{300, 200}, []int64{400},
+			[]int64{350, 300, 250, 200}, []int64{250, 350, 300, 200, 400}},
+		{"duplicates", nil, []int64{200, 200, 300}, []int64{300, 200, 100}, []int64{300, 400, 400},
+			[]int64{300, 200, 100}, []int64{200, 300, 100, 400}},
+		{"missing timestamps", nil, []int64{0, 200}, []int64{300, 0, -1}, []int64{0},
+			[]int64{300, 200}, []int64{200, 300}},
+		{"earlier run", []int64{100, 200}, []int64{300}, []int64{400, 200}, []int64{500},
+			[]int64{400, 300, 200, 100}, []int64{100, 200, 300, 400, 500}},
+	}
+	for _, tt := range tests {
+		record := filepath.Join(t.TempDir(), "flight.json")
+		for _, ts := range tt.inFile {
+			appendLine(t, TrailFile(record), ts)
+		}
+		tr := newTrails()
+		for _, ts := range tt.before {
+			tr.addFeed(&FeedFlight{ID: "f", Timestamp: ts})
+		}
+		details := &FlightDetails{}
+		for _, ts := range tt.details {
+			details.Trail = append(details.Trail, TrailPoint{Ts: ts})
+		}
+		merged, err := tr.addDetails("f", record, details)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for _, ts := range tt.after {
+			if err := tr.addFeed(&FeedFlight{ID: "f", Timestamp: ts}); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+		}
+
+		if got := timestamps(merged); !slices.Equal(got, tt.merged) {
+			t.Errorf("%s: merged %v, want %v", tt.name, got, tt.merged)
+		}
+		if got := readLines(t, TrailFile(record)); !slices.Equal(got, tt.lines) {
+			t.Errorf("%s: trail file %v, want %v", tt.name, got, tt.lines)
+		}
+		stored, err := ReadTrail(TrailFile(record))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		want := slices.Clone(tt.lines)
+		slices.Sort(want)
+		if got := timestamps(stored); !slices.Equal(got, want) {
+			t.Errorf("%s: ReadTrail %v, want %v", tt.name, got, want)
+		}
+	}
+}
+
+func timestamps(points []TrailPoint) []int64 {
+	var ts []int64
+	for _, p := range points {
+		ts = append(ts, p.Ts)
+	}
+	return ts
+}
+
+func appendLine(t *testing.T, file string, ts int64) {
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := json.NewEncoder(f).Encode(TrailPoint{Ts: ts}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readLines returns the timestamp of every line, duplicates included.
+func readLines(t *testing.T, file string) []int64 {
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var ts []int64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var p TrailPoint
+		if err := json.Unmarshal(scanner.Bytes(), &p); err != nil {
+			t.Fatal(err)
+		}
+		ts = append(ts, p.Ts)
+	}
+	return ts
+}
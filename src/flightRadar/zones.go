@@ -0,0 +1,153 @@
+package flightRadar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+const zonesURL = "https://www.flightradar24.com/js/zones.js.php"
+
+// Zone is one of the named areas FR24 uses for its own map, e.g. "europe"
+// or its subzone "poland".
+type Zone struct {
+	Bound
+	Subzones map[string]Zone `json:"subzones,omitempty"`
+}
+
+// Zones maps top level zone names to their definition.
+type Zones map[string]Zone
+
+// ParseZones decodes the zones.js.php payload. Non-zone keys such as
+// "version" are skipped.
+func ParseZones(body []byte) (Zones, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+	zones := make(Zones)
+	for name, value := range raw {
+		if len(value) == 0 || value[0] != '{' {
+			continue
+		}
+		var z Zone
+		if err := json.Unmarshal(value, &z); err != nil {
+			return nil, fmt.Errorf("zone %s: %v", name, err)
+		}
+		zones[name] = z
+	}
+	return zones, nil
+}
+
+// Zones fetches the current zone list from FR24. The raw body is returned as
+// well so it can be saved for later runs.
+func (c *Client) Zones(ctx context.Context) (Zones, []byte, error) {
+	body, err := c.get(ctx, zonesURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	zones, err := ParseZones(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return zones, body, nil
+}
+
+// LoadZones reads a saved copy of zones.js.php.
+func LoadZones(file string) (Zones, error) {
+	body, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParseZones(body)
+}
+
+// loadOrFetchZones reads file when it exists, otherwise fetches the zones and
+// saves them to file (if set) so the next run does not hit FR24.
+func loadOrFetchZones(ctx context.Context, client *Client, file string) (Zones, error) {
+	if file != "" {
+		if _, err := os.Stat(file); err == nil {
+			return LoadZones(file)
+		}
+	}
+	zones, body, err := client.Zones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if file != "" {
+		if err := ioutil.WriteFile(file, body, 0644); err != nil {
+			fmt.Println("[Err] Could not save zones file", err)
+		}
+	}
+	return zones, nil
+}
+
+// Find looks a zone up by name among the top level zones and their subzones.
+// A subzone name used under more than one parent is found under the first
+// parent in name order.
+func (zs Zones) Find(name string) (Zone, bool) {
+	if z, ok := zs[name]; ok {
+		return z, true
+	}
+	for _, parent := range zs.sorted() {
+		if sub, ok := Zones(zs[parent].Subzones).Find(name); ok {
+			return sub, true
+		}
+	}
+	return Zone{}, false
+}
+
+// sorted returns the zone names in order.
+func (zs Zones) sorted() []string {
+	names := make([]string, 0, len(zs))
+	for name := range zs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Names lists every zone and subzone name, sorted.
+func (zs Zones) Names() []string {
+	var names []string
+	for name, z := range zs {
+		names = append(names, name)
+		names = append(names, Zones(z.Subzones).Names()...)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Bounds turns the named zones into tiles for a sweep. A zone is always
+// swept through its own bound, since FR24's subzones do not cover their
+// parent ("uk" only has "london" and "ireland"). Its subzones are swept as
+// well: the feed caps how many aircraft a single request returns, so busy
+// areas get requests of their own.
+func (zs Zones) Bounds(names ...string) ([]Bound, error) {
+	var bounds []Bound
+	seen := make(map[Bound]bool)
+	for _, name := range names {
+		z, ok := zs.Find(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown zone %q", name)
+		}
+		for _, b := range z.bounds() {
+			if !seen[b] {
+				seen[b] = true
+				bounds = append(bounds, b)
+			}
+		}
+	}
+	return bounds, nil
+}
+
+func (z Zone) bounds() []Bound {
+	bounds := []Bound{z.Bound}
+	for _, name := range Zones(z.Subzones).sorted() {
+		bounds = append(bounds, z.Subzones[name].bounds()...)
+	}
+	return bounds
+}
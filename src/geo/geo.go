@@ -0,0 +1,90 @@
+// Package geo holds the spherical earth geometry the analysis packages
+// share. Distances are in meters and angles in degrees.
+package geo
+
+import "math"
+
+// EarthRadius is the mean earth radius in meters.
+const EarthRadius = 6371008.8
+
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+func rad(deg float64) float64 { return deg * math.Pi / 180 }
+func deg(rad float64) float64 { return rad * 180 / math.Pi }
+
+// Distance is the great circle distance between a and b.
+func Distance(a, b Point) float64 {
+	return EarthRadius * angle(a, b)
+}
+
+// angle is the central angle between a and b in radians.
+func angle(a, b Point) float64 {
+	dLat := rad(b.Lat - a.Lat)
+	dLon := rad(b.Lon - a.Lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(rad(a.Lat))*math.Cos(rad(b.Lat))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Extent is how far, in degrees of latitude and longitude, the circle of
+// radius meters around p reaches from it. Longitude is the widest point of
+// the circle, which lies poleward of p; it is 180 when the circle holds a
+// pole.
+func Extent(p Point, radius float64) (dLat, dLon float64) {
+	d := radius / EarthRadius
+	dLat = deg(d)
+	dLon = 180
+	if s := math.Sin(d) / math.Cos(rad(p.Lat)); d < math.Pi/2 && s < 1 {
+		dLon = deg(math.Asin(s))
+	}
+	return dLat, dLon
+}
+
+// Bearing is the initial true course from a to b, in [0, 360).
+func Bearing(a, b Point) float64 {
+	lat1, lat2 := rad(a.Lat), rad(b.Lat)
+	dLon := rad(b.Lon - a.Lon)
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	return math.Mod(deg(math.Atan2(y, x))+360, 360)
+}
+
+// Interpolate returns the point a fraction f of the way from a to b along
+// the great circle.
+func Interpolate(a, b Point, f float64) Point {
+	d := angle(a, b)
+	if d < 1e-12 {
+		return a
+	}
+	lat1, lon1, lat2, lon2 := rad(a.Lat), rad(a.Lon), rad(b.Lat), rad(b.Lon)
+	wa := math.Sin((1-f)*d) / math.Sin(d)
+	wb := math.Sin(f*d) / math.Sin(d)
+	x := wa*math.Cos(lat1)*math.Cos(lon1) + wb*math.Cos(lat2)*math.Cos(lon2)
+	y := wa*math.Cos(lat1)*math.Sin(lon1) + wb*math.Cos(lat2)*math.Sin(lon2)
+	z := wa*math.Sin(lat1) + wb*math.Sin(lat2)
+	return Point{deg(math.Atan2(z, math.Hypot(x, y))), deg(math.Atan2(y, x))}
+}
+
+// SegmentDistance is the distance from p to the great circle segment a-b,
+// and how far along the segment from a the closest point lies.
+func SegmentDistance(p, a, b Point) (distance, along float64) {
+	d12 := angle(a, b)
+	d13 := angle(a, p)
+	if d12 < 1e-12 {
+		return EarthRadius * d13, 0
+	}
+	course := rad(Bearing(a, p) - Bearing(a, b))
+	xt := math.Asin(math.Sin(d13) * math.Sin(course))
+	at := math.Acos(math.Max(-1, math.Min(1, math.Cos(d13)/math.Cos(xt))))
+	switch {
+	case math.Cos(course) < 0:
+		// p lies behind a.
+		return EarthRadius * d13, 0
+	case at > d12:
+		return Distance(p, b), EarthRadius * d12
+	}
+	return EarthRadius * math.Abs(xt), EarthRadius * at
+}
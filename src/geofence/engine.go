@@ -0,0 +1,187 @@
+package geofence
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"radar/geo"
+	"radar/model"
+)
+
+const (
+	Enter = "enter"
+	Exit  = "exit"
+	Dwell = "dwell"
+)
+
+// maxCells is how many one degree grid cells a fence may cover before it is
+// checked against every position instead.
+const maxCells = 400
+
+type Event struct {
+	Type         string         `json:"type"`
+	Fence        string         `json:"fence"`
+	FlightID     string         `json:"flight_id"`
+	Callsign     string         `json:"callsign,omitempty"`
+	Registration string         `json:"registration,omitempty"`
+	Hex          string         `json:"hex,omitempty"`
+	Time         time.Time      `json:"time"`
+	Position     model.Position `json:"position"`
+	// Inside is how long the aircraft had been inside, for dwell and exit
+	// events, in seconds.
+	Inside int64 `json:"inside_seconds,omitempty"`
+	// Lost is set on exits of aircraft that dropped out of the feed.
+	Lost bool `json:"lost,omitempty"`
+}
+
+func (e Event) String() string {
+	return fmt.Sprintf("%s %s %s %s at %.4f,%.4f %dft", e.Fence, e.Type, e.Callsign, e.FlightID,
+		e.Position.Lat, e.Position.Lon, e.Position.Altitude)
+}
+
+type cell struct{ lat, lon int }
+
+func cellOf(p geo.Point) cell {
+	return cell{int(math.Floor(p.Lat)), wrap(int(math.Floor(p.Lon)))}
+}
+
+// wrap keeps longitude cells in [-180, 180), so boxes reaching past the
+// antimeridian land in the cells on the other side.
+func wrap(lon int) int {
+	return ((lon+180)%360+360)%360 - 180
+}
+
+// Engine checks positions against fences. Fences are bucketed by the one
+// degree cells their bounding box covers, so a position is only tested
+// against the few fences near it.
+type Engine struct {
+	fences []*Fence
+	grid   map[cell][]*Fence
+	wide   []*Fence
+
+	mu      sync.Mutex
+	flights map[string]*aircraft
+}
+
+type aircraft struct {
+	flight model.Flight
+	inside map[string]*stay
+}
+
+type stay struct {
+	since   time.Time
+	dwelled bool
+}
+
+func NewEngine(fences []*Fence) *Engine {
+	e := &Engine{fences: fences, grid: make(map[cell][]*Fence), flights: make(map[string]*aircraft)}
+	for _, f := range fences {
+		lo := cell{int(math.Floor(f.box.minLat)), int(math.Floor(f.box.minLon))}
+		hi := cell{int(math.Floor(f.box.maxLat)), int(math.Floor(f.box.maxLon))}
+		if (hi.lat-lo.lat+1)*(hi.lon-lo.lon+1) > maxCells {
+			e.wide = append(e.wide, f)
+			continue
+		}
+		for lat := lo.lat; lat <= hi.lat; lat++ {
+			for lon := lo.lon; lon <= hi.lon && lon < lo.lon+360; lon++ {
+				c := cell{lat, wrap(lon)}
+				e.grid[c] = append(e.grid[c], f)
+			}
+		}
+	}
+	return e
+}
+
+func (e *Engine) Len() int {
+	return len(e.fences)
+}
+
+// Update moves a flight to its current position and returns the events
+// that causes. Positions older than the last one seen are ignored, so a
+// flight reported by overlapping tiles is only evaluated once.
+func (e *Engine) Update(flight *model.Flight) []Event {
+	pos := flight.Position
+	if pos == nil {
+		return nil
+	}
+	p := geo.Point{Lat: pos.Lat, Lon: pos.Lon}
+	in := make(map[string]*Fence)
+	for _, list := range [][]*Fence{e.grid[cellOf(p)], e.wide} {
+		for _, f := range list {
+			if f.Contains(p, pos.Altitude) {
+				in[f.Name] = f
+			}
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	a, ok := e.flights[flight.ID]
+	if !ok {
+		a = &aircraft{inside: make(map[string]*stay)}
+		e.flights[flight.ID] = a
+	} else if a.flight.Position != nil && !pos.Time.After(a.flight.Position.Time) {
+		return nil
+	}
+	a.flight = *flight
+
+	var events []Event
+	for name, s := range a.inside {
+		if in[name] == nil {
+			events = append(events, a.event(Exit, name, pos.Time, s))
+			delete(a.inside, name)
+		}
+	}
+	for name, f := range in {
+		s, ok := a.inside[name]
+		if !ok {
+			s = &stay{since: pos.Time}
+			a.inside[name] = s
+			events = append(events, a.event(Enter, name, pos.Time, nil))
+		}
+		if f.Dwell > 0 && !s.dwelled && pos.Time.Sub(s.since) >= f.Dwell {
+			s.dwelled = true
+			events = append(events, a.event(Dwell, name, pos.Time, s))
+		}
+	}
+	return events
+}
+
+// Expire forgets flights not updated for maxAge and returns exit events for
+// the fences they were still in.
+func (e *Engine) Expire(maxAge time.Duration) []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var events []Event
+	for id, a := range e.flights {
+		if a.flight.Position == nil || time.Since(a.flight.Position.Time) <= maxAge {
+			continue
+		}
+		for name, s := range a.inside {
+			event := a.event(Exit, name, a.flight.Position.Time, s)
+			event.Lost = true
+			events = append(events, event)
+		}
+		delete(e.flights, id)
+	}
+	return events
+}
+
+func (a *aircraft) event(kind, fence string, at time.Time, s *stay) Event {
+	event := Event{
+		Type:         kind,
+		Fence:        fence,
+		FlightID:     a.flight.ID,
+		Callsign:     a.flight.Callsign,
+		Registration: a.flight.Aircraft.Registration,
+		Hex:          a.flight.Aircraft.Hex,
+		Time:         at,
+		Position:     *a.flight.Position,
+	}
+	if s != nil {
+		event.Inside = int64(at.Sub(s.since) / time.Second)
+	}
+	return event
+}
@@ -0,0 +1,293 @@
+// Package geofence raises events when aircraft enter, leave or dwell in
+// named polygons and circles, optionally limited to an altitude band.
+package geofence
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"time"
+
+	"radar/geo"
+)
+
+// Fence is a named area. Exactly one of Polygon and Circle is set. Circles
+// may cross the antimeridian, polygons may not.
+type Fence struct {
+	Name string
+	// Polygon holds one or more polygons, each an outer ring followed by
+	// its holes. Rings need not be closed.
+	Polygon [][][]geo.Point
+	Circle  *Circle
+	// MinAltitude and MaxAltitude, in feet, limit the fence to a band.
+	MinAltitude *int
+	MaxAltitude *int
+	// Dwell raises a dwell event once an aircraft has been inside this
+	// long. Zero disables dwell events.
+	Dwell time.Duration
+
+	box box
+}
+
+type Circle struct {
+	Center  geo.Point
+	RadiusM float64
+}
+
+// box is a fence's bounding box in degrees. The box of a circle near the
+// antimeridian reaches past ±180.
+type box struct {
+	minLat, minLon, maxLat, maxLon float64
+}
+
+func (b box) contains(p geo.Point) bool {
+	if p.Lat < b.minLat || p.Lat > b.maxLat {
+		return false
+	}
+	for _, lon := range []float64{p.Lon, p.Lon - 360, p.Lon + 360} {
+		if lon >= b.minLon && lon <= b.maxLon {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether an aircraft at p and altitude (feet) is inside.
+func (f *Fence) Contains(p geo.Point, altitude int) bool {
+	if f.MinAltitude != nil && altitude < *f.MinAltitude {
+		return false
+	}
+	if f.MaxAltitude != nil && altitude > *f.MaxAltitude {
+		return false
+	}
+	if !f.box.contains(p) {
+		return false
+	}
+	if f.Circle != nil {
+		return geo.Distance(p, f.Circle.Center) <= f.Circle.RadiusM
+	}
+	for _, polygon := range f.Polygon {
+		if len(polygon) == 0 || !inRing(p, polygon[0]) {
+			continue
+		}
+		inHole := false
+		for _, hole := range polygon[1:] {
+			if inRing(p, hole) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// inRing is the even-odd rule on the lat/lon plane, which is accurate
+// enough for fences that do not cross the antimeridian.
+func inRing(p geo.Point, ring []geo.Point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// prepare validates the fence and computes its bounding box.
+func (f *Fence) prepare() error {
+	if f.Name == "" {
+		return fmt.Errorf("geofence without a name")
+	}
+	if (f.Circle == nil) == (len(f.Polygon) == 0) {
+		return fmt.Errorf("geofence %q needs either a polygon or a circle", f.Name)
+	}
+	if f.Circle != nil {
+		if f.Circle.RadiusM <= 0 {
+			return fmt.Errorf("geofence %q has no radius", f.Name)
+		}
+		dLat, dLon := geo.Extent(f.Circle.Center, f.Circle.RadiusM)
+		f.box = box{f.Circle.Center.Lat - dLat, f.Circle.Center.Lon - dLon, f.Circle.Center.Lat + dLat, f.Circle.Center.Lon + dLon}
+		return nil
+	}
+	f.box = box{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, polygon := range f.Polygon {
+		if len(polygon) == 0 || len(polygon[0]) < 3 {
+			return fmt.Errorf("geofence %q has a ring with fewer than 3 points", f.Name)
+		}
+		for _, p := range polygon[0] {
+			f.box.minLat = math.Min(f.box.minLat, p.Lat)
+			f.box.minLon = math.Min(f.box.minLon, p.Lon)
+			f.box.maxLat = math.Max(f.box.maxLat, p.Lat)
+			f.box.maxLon = math.Max(f.box.maxLon, p.Lon)
+		}
+	}
+	return nil
+}
+
+// config is the plain JSON fence file:
+//
+//	{"fences": [
+//	  {"name": "EGLL", "circle": {"lat": 51.47, "lon": -0.45, "radius_m": 20000},
+//	   "max_altitude": 6000, "dwell": "15m"},
+//	  {"name": "box", "polygon": [[lon, lat], [lon, lat], [lon, lat]]}
+//	]}
+//
+// Polygon coordinates are [lon, lat] as in GeoJSON.
+type config struct {
+	Fences []configFence `json:"fences"`
+}
+
+type configFence struct {
+	Name    string      `json:"name"`
+	Polygon [][]float64 `json:"polygon"`
+	Circle  *struct {
+		Lat     float64 `json:"lat"`
+		Lon     float64 `json:"lon"`
+		RadiusM float64 `json:"radius_m"`
+	} `json:"circle"`
+	properties
+}
+
+// properties are shared by config fences and GeoJSON feature properties.
+type properties struct {
+	Name        string  `json:"name"`
+	RadiusM     float64 `json:"radius_m"`
+	MinAltitude *int    `json:"min_altitude"`
+	MaxAltitude *int    `json:"max_altitude"`
+	Dwell       string  `json:"dwell"`
+}
+
+func (p properties) apply(f *Fence) error {
+	f.MinAltitude, f.MaxAltitude = p.MinAltitude, p.MaxAltitude
+	if p.Dwell != "" {
+		d, err := time.ParseDuration(p.Dwell)
+		if err != nil {
+			return fmt.Errorf("geofence %q: %v", f.Name, err)
+		}
+		f.Dwell = d
+	}
+	return nil
+}
+
+type geoJSON struct {
+	Type     string `json:"type"`
+	Features []struct {
+		Geometry struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+		Properties properties `json:"properties"`
+	} `json:"features"`
+}
+
+// Load reads fences from a GeoJSON FeatureCollection or a plain config
+// file. GeoJSON Polygons and MultiPolygons are polygons; Points with a
+// radius_m property are circles. Features are named by their name property.
+func Load(file string) ([]*Fence, error) {
+	body, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var collection geoJSON
+	if err := json.Unmarshal(body, &collection); err != nil {
+		return nil, err
+	}
+	var fences []*Fence
+	if collection.Type == "FeatureCollection" {
+		for i, feature := range collection.Features {
+			f := &Fence{Name: feature.Properties.Name}
+			if f.Name == "" {
+				f.Name = fmt.Sprintf("feature-%d", i)
+			}
+			if err := decodeGeometry(f, feature.Geometry.Type, feature.Geometry.Coordinates, feature.Properties.RadiusM); err != nil {
+				return nil, err
+			}
+			if err := feature.Properties.apply(f); err != nil {
+				return nil, err
+			}
+			fences = append(fences, f)
+		}
+	} else {
+		var c config
+		if err := json.Unmarshal(body, &c); err != nil {
+			return nil, err
+		}
+		for _, cf := range c.Fences {
+			f := &Fence{Name: cf.Name}
+			if cf.Circle != nil {
+				f.Circle = &Circle{Center: geo.Point{Lat: cf.Circle.Lat, Lon: cf.Circle.Lon}, RadiusM: cf.Circle.RadiusM}
+			}
+			if len(cf.Polygon) > 0 {
+				f.Polygon = [][][]geo.Point{{points(cf.Polygon)}}
+			}
+			if err := cf.properties.apply(f); err != nil {
+				return nil, err
+			}
+			fences = append(fences, f)
+		}
+	}
+	names := make(map[string]bool)
+	for _, f := range fences {
+		if err := f.prepare(); err != nil {
+			return nil, err
+		}
+		if names[f.Name] {
+			return nil, fmt.Errorf("duplicate geofence %q", f.Name)
+		}
+		names[f.Name] = true
+	}
+	return fences, nil
+}
+
+func decodeGeometry(f *Fence, kind string, coordinates json.RawMessage, radius float64) error {
+	switch kind {
+	case "Point":
+		var c []float64
+		if err := json.Unmarshal(coordinates, &c); err != nil || len(c) < 2 {
+			return fmt.Errorf("geofence %q: bad point", f.Name)
+		}
+		f.Circle = &Circle{Center: geo.Point{Lat: c[1], Lon: c[0]}, RadiusM: radius}
+	case "Polygon":
+		var rings [][][]float64
+		if err := json.Unmarshal(coordinates, &rings); err != nil {
+			return fmt.Errorf("geofence %q: %v", f.Name, err)
+		}
+		f.Polygon = [][][]geo.Point{polygon(rings)}
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err := json.Unmarshal(coordinates, &polygons); err != nil {
+			return fmt.Errorf("geofence %q: %v", f.Name, err)
+		}
+		for _, rings := range polygons {
+			f.Polygon = append(f.Polygon, polygon(rings))
+		}
+	default:
+		return fmt.Errorf("geofence %q: unsupported geometry %q", f.Name, kind)
+	}
+	return nil
+}
+
+func polygon(rings [][][]float64) [][]geo.Point {
+	out := make([][]geo.Point, len(rings))
+	for i, ring := range rings {
+		out[i] = points(ring)
+	}
+	return out
+}
+
+// points converts GeoJSON [lon, lat] pairs.
+func points(coordinates [][]float64) []geo.Point {
+	var out []geo.Point
+	for _, c := range coordinates {
+		if len(c) >= 2 {
+			out = append(out, geo.Point{Lat: c[1], Lon: c[0]})
+		}
+	}
+	return out
+}
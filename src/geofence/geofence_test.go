@@ -0,0 +1,126 @@
+package geofence
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"radar/geo"
+	"radar/model"
+)
+
+func ring(lonLat ...float64) []geo.Point {
+	var points []geo.Point
+	for i := 0; i+1 < len(lonLat); i += 2 {
+		points = append(points, geo.Point{Lat: lonLat[i+1], Lon: lonLat[i]})
+	}
+	return points
+}
+
+func altitude(ft int) *int { return &ft }
+
+func prepared(t *testing.T, f *Fence) *Fence {
+	if err := f.prepare(); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestContains(t *testing.T) {
+	square := prepared(t, &Fence{Name: "square", Polygon: [][][]geo.Point{{ring(0, 0, 2, 0, 2, 2, 0, 2)}}})
+	donut := prepared(t, &Fence{Name: "donut", Polygon: [][][]geo.Point{{ring(0, 0, 4, 0, 4, 4, 0, 4), ring(1, 1, 3, 1, 3, 3, 1, 3)}}})
+	islands := prepared(t, &Fence{Name: "islands", Polygon: [][][]geo.Point{{ring(0, 0, 1, 0, 1, 1, 0, 1)}, {ring(5, 5, 6, 5, 6, 6, 5, 6)}}})
+	band := prepared(t, &Fence{Name: "band", Polygon: [][][]geo.Point{{ring(0, 0, 2, 0, 2, 2, 0, 2)}}, MinAltitude: altitude(1000), MaxAltitude: altitude(5000)})
+	circle := prepared(t, &Fence{Name: "circle", Circle: &Circle{Center: geo.Point{Lat: 51.47, Lon: -0.45}, RadiusM: 20000}})
+	dateline := prepared(t, &Fence{Name: "dateline", Circle: &Circle{Center: geo.Point{Lat: -17, Lon: 179.9}, RadiusM: 50000}})
+
+	tests := []struct {
+		name     string
+		fence    *Fence
+		lat, lon float64
+		altitude int
+		want     bool
+	}{
+		{"inside the square", square, 1, 1, 0, true},
+		{"outside the square", square, 1, 3, 0, false},
+		{"beside the square within its box", square, 2.5, 1, 0, false},
+		{"in the donut", donut, 0.5, 0.5, 0, true},
+		{"in the donut's hole", donut, 2, 2, 0, false},
+		{"on the first island", islands, 0.5, 0.5, 0, true},
+		{"on the second island", islands, 5.5, 5.5, 0, true},
+		{"between the islands", islands, 3, 3, 0, false},
+		{"in the band", band, 1, 1, 3000, true},
+		{"below the band", band, 1, 1, 999, false},
+		{"above the band", band, 1, 1, 5001, false},
+		{"at the band's edges", band, 1, 1, 5000, true},
+		{"in the circle", circle, 51.5, -0.3, 0, true},
+		{"outside the circle", circle, 51.5, 0, 0, false},
+		{"in the circle across the antimeridian", dateline, -17, -179.9, 0, true},
+		{"in the circle on its own side", dateline, -17, 179.7, 0, true},
+		{"beyond the circle across the antimeridian", dateline, -17, -179.3, 0, false},
+	}
+	for _, tt := range tests {
+		if got := tt.fence.Contains(geo.Point{Lat: tt.lat, Lon: tt.lon}, tt.altitude); got != tt.want {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	fences := []*Fence{
+		prepared(t, &Fence{Name: "square", Polygon: [][][]geo.Point{{ring(0, 0, 2, 0, 2, 2, 0, 2)}}, Dwell: 10 * time.Minute}),
+		prepared(t, &Fence{Name: "dateline", Circle: &Circle{Center: geo.Point{Lat: -17, Lon: 179.9}, RadiusM: 50000}}),
+	}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	steps := []struct {
+		minute   int
+		lat, lon float64
+		want     []string // fence and type of every event
+	}{
+		{0, 1, -1, nil},
+		{1, 1, 1, []string{"square enter"}},
+		{6, 1, 1.5, nil},
+		{5, 1, 3, nil}, // older than the last position
+		{11, 1, 1.8, []string{"square dwell"}},
+		{12, 1, 1.9, nil},
+		{13, 1, 3, []string{"square exit"}},
+		{14, 1, 1, []string{"square enter"}},
+		{15, -17, -179.95, []string{"dateline enter", "square exit"}},
+	}
+	e := NewEngine(fences)
+	for _, step := range steps {
+		flight := &model.Flight{ID: "f", Position: &model.Position{
+			Time: start.Add(time.Duration(step.minute) * time.Minute), Lat: step.lat, Lon: step.lon,
+		}}
+		var got []string
+		for _, event := range e.Update(flight) {
+			got = append(got, event.Fence+" "+event.Type)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, step.want) {
+			t.Errorf("minute %d: events %v, want %v", step.minute, got, step.want)
+		}
+	}
+
+	lost := e.Expire(time.Hour)
+	if len(lost) != 1 || lost[0].Fence != "dateline" || lost[0].Type != Exit || !lost[0].Lost {
+		t.Errorf("expire: events %+v, want a lost dateline exit", lost)
+	}
+	if again := e.Expire(time.Hour); len(again) != 0 {
+		t.Errorf("expire again: events %+v, want none", again)
+	}
+}
+
+func TestCircleEdge(t *testing.T) {
+	// The widest point of a circle lies poleward of its center, further
+	// east than its radius over the cosine of the center's latitude.
+	circle := prepared(t, &Fence{Name: "north", Circle: &Circle{Center: geo.Point{Lat: 70, Lon: 20}, RadiusM: 500000}})
+	for _, p := range []geo.Point{{Lat: 70.49, Lon: 33.2}, {Lat: 70.49, Lon: 6.8}} {
+		if d := geo.Distance(p, circle.Circle.Center); d > circle.Circle.RadiusM {
+			t.Fatalf("%v is %.0fm from the center", p, d)
+		}
+		if !circle.Contains(p, 0) {
+			t.Errorf("%v near the widest point is not inside", p)
+		}
+	}
+}
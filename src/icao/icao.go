@@ -0,0 +1,247 @@
+// Package icao decodes 24-bit ICAO aircraft addresses and cross-checks them
+// against the registration and country a source reports.
+package icao
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"radar/model"
+)
+
+// ParseHex reads an address as FR24 reports it, e.g. "4D03C6".
+func ParseHex(hex string) (uint32, error) {
+	hex = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(hex)), "0x")
+	v, err := strconv.ParseUint(hex, 16, 24)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ICAO address %q", hex)
+	}
+	return uint32(v), nil
+}
+
+// Lookup returns the allocation an address belongs to.
+func Lookup(addr uint32) (Allocation, bool) {
+	return search(allocations, addr)
+}
+
+// Military returns the military block an address belongs to, if any.
+func Military(addr uint32) (Allocation, bool) {
+	return search(military, addr)
+}
+
+func search(blocks []Allocation, addr uint32) (Allocation, bool) {
+	i := sort.Search(len(blocks), func(i int) bool { return blocks[i].End >= addr })
+	if i < len(blocks) && blocks[i].Start <= addr {
+		return blocks[i], true
+	}
+	return Allocation{}, false
+}
+
+// Country is the state an address was allocated to, "" when unknown.
+func Country(hex string) string {
+	addr, err := ParseHex(hex)
+	if err != nil {
+		return ""
+	}
+	a, _ := Lookup(addr)
+	return a.Country
+}
+
+// RegistrationCountries are the states whose nationality marks start reg,
+// using the longest matching mark. It is nil for registrations without a
+// known mark, such as military serials.
+func RegistrationCountries(reg string) []string {
+	reg = strings.ToUpper(strings.TrimSpace(reg))
+	var countries []string
+	best := 0
+	for _, a := range allocations {
+		for _, p := range a.Prefixes {
+			if len(p) < best || !strings.HasPrefix(reg, p) {
+				continue
+			}
+			if len(p) > best {
+				best, countries = len(p), nil
+			}
+			countries = append(countries, a.Country)
+		}
+	}
+	return countries
+}
+
+// Registration derives the registration of addresses in blocks that assign
+// them algorithmically. Only US N-numbers are supported.
+func Registration(hex string) (string, bool) {
+	addr, err := ParseHex(hex)
+	if err != nil {
+		return "", false
+	}
+	return NNumber(addr)
+}
+
+// US addresses A00001-ADF7C7 map one to one onto N1-N99999, ordered as the
+// FAA registry sorts N-numbers: a registration is followed by its letter
+// suffixes, then by the registrations one digit longer.
+const (
+	nNumberFirst = 0xA00001
+	nNumberLast  = 0xADF7C7
+	nLetters     = "ABCDEFGHJKLMNPQRSTUVWXYZ" // no I or O
+	// nSuffixSize counts "", "A", "AA".."AZ", "B", ... after a digit.
+	nSuffixSize  = 1 + len(nLetters)*(1+len(nLetters))
+	nBucket4Size = 1 + len(nLetters) + 10
+	nBucket3Size = 10*nBucket4Size + nSuffixSize
+	nBucket2Size = 10*nBucket3Size + nSuffixSize
+	nBucket1Size = 10*nBucket2Size + nSuffixSize
+)
+
+// NNumber converts a US address to its N-number.
+func NNumber(addr uint32) (string, bool) {
+	if addr < nNumberFirst || addr > nNumberLast {
+		return "", false
+	}
+	o := int(addr - nNumberFirst)
+	reg := "N" + strconv.Itoa(o/nBucket1Size+1)
+	o %= nBucket1Size
+	for _, size := range []int{nBucket2Size, nBucket3Size, nBucket4Size} {
+		if o < nSuffixSize {
+			return reg + nSuffix(o), true
+		}
+		o -= nSuffixSize
+		reg += strconv.Itoa(o / size)
+		o %= size
+	}
+	// After the fifth character only a single letter or digit may follow.
+	switch {
+	case o == 0:
+	case o <= len(nLetters):
+		reg += nLetters[o-1 : o]
+	default:
+		reg += strconv.Itoa(o - len(nLetters) - 1)
+	}
+	return reg, true
+}
+
+func nSuffix(o int) string {
+	if o == 0 {
+		return ""
+	}
+	o--
+	first, second := o/(1+len(nLetters)), o%(1+len(nLetters))
+	s := nLetters[first : first+1]
+	if second > 0 {
+		s += nLetters[second-1 : second]
+	}
+	return s
+}
+
+const (
+	// MismatchRegistration means the registration's nationality mark
+	// belongs to another state than the address block.
+	MismatchRegistration = "registration_country"
+	// MismatchNNumber means a US address does not decode to the reported
+	// N-number.
+	MismatchNNumber = "n_number"
+	// MismatchCountryID means the source's country ID usually goes with
+	// addresses of another state.
+	MismatchCountryID = "country_id"
+)
+
+type Mismatch struct {
+	Kind     string `json:"kind"`
+	Expected string `json:"expected"`
+	Got      string `json:"got"`
+}
+
+// Result is what Check derives from an aircraft's address.
+type Result struct {
+	Country string `json:"country,omitempty"`
+	// Registration is derived from the address where the state assigns
+	// them algorithmically.
+	Registration string     `json:"registration,omitempty"`
+	Mismatches   []Mismatch `json:"mismatches,omitempty"`
+}
+
+// minCountryVotes is how often a country ID must be seen with addresses of
+// one state before disagreeing with it is flagged.
+const minCountryVotes = 5
+
+// Checker cross-checks hex, registration and country ID. Sources number
+// countries their own way (FR24's countryId), so the Checker learns which
+// state each ID stands for from the records whose address and registration
+// agree.
+type Checker struct {
+	mu    sync.Mutex
+	votes map[int]map[string]int
+}
+
+func NewChecker() *Checker {
+	return &Checker{votes: make(map[int]map[string]int)}
+}
+
+// Check returns nil when the address is missing or not allocated.
+func (c *Checker) Check(a model.Aircraft) *Result {
+	addr, err := ParseHex(a.Hex)
+	if err != nil {
+		return nil
+	}
+	alloc, ok := Lookup(addr)
+	if !ok {
+		return nil
+	}
+	r := &Result{Country: alloc.Country}
+	r.Registration, _ = NNumber(addr)
+
+	reg := strings.ToUpper(strings.TrimSpace(a.Registration))
+	consistent := true
+	if reg != "" {
+		if countries := RegistrationCountries(reg); len(countries) > 0 && !contains(countries, alloc.Country) {
+			consistent = false
+			r.Mismatches = append(r.Mismatches, Mismatch{MismatchRegistration, alloc.Country, strings.Join(countries, "/")})
+		}
+		if r.Registration != "" && strings.HasPrefix(reg, "N") && reg != r.Registration {
+			consistent = false
+			r.Mismatches = append(r.Mismatches, Mismatch{MismatchNNumber, r.Registration, reg})
+		}
+	}
+
+	if a.CountryID != 0 && c != nil {
+		c.mu.Lock()
+		if learned := c.country(a.CountryID); learned != "" && learned != alloc.Country {
+			r.Mismatches = append(r.Mismatches, Mismatch{MismatchCountryID, alloc.Country, fmt.Sprintf("%d (%s)", a.CountryID, learned)})
+		} else if consistent && reg != "" {
+			if c.votes[a.CountryID] == nil {
+				c.votes[a.CountryID] = make(map[string]int)
+			}
+			c.votes[a.CountryID][alloc.Country]++
+		}
+		c.mu.Unlock()
+	}
+	return r
+}
+
+// country is the state a country ID has been seen with in a clear majority
+// of at least minCountryVotes records.
+func (c *Checker) country(id int) string {
+	best, total := "", 0
+	for country, n := range c.votes[id] {
+		total += n
+		if n > c.votes[id][best] {
+			best = country
+		}
+	}
+	if n := c.votes[id][best]; n >= minCountryVotes && 2*n > total {
+		return best
+	}
+	return ""
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,247 @@
+package icao
+
+// Allocation is a block of 24-bit addresses ICAO assigned to a state, with
+// the nationality marks registrations of that state start with.
+type Allocation struct {
+	Start, End uint32
+	Country    string
+	Prefixes   []string
+}
+
+// allocations follow ICAO Annex 10 Vol III, ordered by Start. Blocks of
+// states whose marks are unknown to us keep an empty Prefixes.
+var allocations = []Allocation{
+	{0x004000, 0x0043FF, "Zimbabwe", []string{"Z-"}},
+	{0x006000, 0x006FFF, "Mozambique", []string{"C9-"}},
+	{0x008000, 0x00FFFF, "South Africa", []string{"ZS-", "ZT-", "ZU-"}},
+	{0x010000, 0x017FFF, "Egypt", []string{"SU-"}},
+	{0x018000, 0x01FFFF, "Libya", []string{"5A-"}},
+	{0x020000, 0x027FFF, "Morocco", []string{"CN-"}},
+	{0x028000, 0x02FFFF, "Tunisia", []string{"TS-"}},
+	{0x030000, 0x0303FF, "Botswana", []string{"A2-"}},
+	{0x032000, 0x032FFF, "Burundi", []string{"9U-"}},
+	{0x034000, 0x034FFF, "Cameroon", []string{"TJ-"}},
+	{0x035000, 0x0353FF, "Comoros", []string{"D6-"}},
+	{0x036000, 0x036FFF, "Congo", []string{"TN-"}},
+	{0x038000, 0x038FFF, "Cote d'Ivoire", []string{"TU-"}},
+	{0x03E000, 0x03EFFF, "Gabon", []string{"TR-"}},
+	{0x040000, 0x040FFF, "Ethiopia", []string{"ET-"}},
+	{0x042000, 0x042FFF, "Equatorial Guinea", []string{"3C-"}},
+	{0x044000, 0x044FFF, "Ghana", []string{"9G-"}},
+	{0x046000, 0x046FFF, "Guinea", []string{"3X-"}},
+	{0x048000, 0x0483FF, "Guinea-Bissau", []string{"J5-"}},
+	{0x04A000, 0x04A3FF, "Lesotho", []string{"7P-"}},
+	{0x04C000, 0x04CFFF, "Kenya", []string{"5Y-"}},
+	{0x050000, 0x050FFF, "Liberia", []string{"A8-"}},
+	{0x054000, 0x054FFF, "Madagascar", []string{"5R-"}},
+	{0x058000, 0x058FFF, "Malawi", []string{"7Q-"}},
+	{0x05A000, 0x05A3FF, "Maldives", []string{"8Q-"}},
+	{0x05C000, 0x05CFFF, "Mali", []string{"TZ-"}},
+	{0x05E000, 0x05E3FF, "Mauritania", []string{"5T-"}},
+	{0x060000, 0x0603FF, "Mauritius", []string{"3B-"}},
+	{0x062000, 0x062FFF, "Niger", []string{"5U-"}},
+	{0x064000, 0x064FFF, "Nigeria", []string{"5N-"}},
+	{0x068000, 0x068FFF, "Uganda", []string{"5X-"}},
+	{0x06A000, 0x06A3FF, "Qatar", []string{"A7-"}},
+	{0x06C000, 0x06CFFF, "Central African Republic", []string{"TL-"}},
+	{0x06E000, 0x06EFFF, "Rwanda", []string{"9XR-"}},
+	{0x070000, 0x070FFF, "Senegal", []string{"6V-", "6W-"}},
+	{0x074000, 0x0743FF, "Seychelles", []string{"S7-"}},
+	{0x076000, 0x0763FF, "Sierra Leone", []string{"9L-"}},
+	{0x078000, 0x078FFF, "Somalia", []string{"6O-"}},
+	{0x07A000, 0x07A3FF, "Eswatini", []string{"3D-"}},
+	{0x07C000, 0x07CFFF, "Sudan", []string{"ST-"}},
+	{0x080000, 0x080FFF, "Tanzania", []string{"5H-"}},
+	{0x084000, 0x084FFF, "Chad", []string{"TT-"}},
+	{0x088000, 0x088FFF, "Togo", []string{"5V-"}},
+	{0x08A000, 0x08AFFF, "Zambia", []string{"9J-"}},
+	{0x08C000, 0x08CFFF, "DR Congo", []string{"9Q-", "9S-"}},
+	{0x090000, 0x090FFF, "Angola", []string{"D2-"}},
+	{0x094000, 0x0943FF, "Benin", []string{"TY-"}},
+	{0x096000, 0x0963FF, "Cape Verde", []string{"D4-"}},
+	{0x098000, 0x0983FF, "Djibouti", []string{"J2-"}},
+	{0x09A000, 0x09AFFF, "Gambia", []string{"C5-"}},
+	{0x09C000, 0x09CFFF, "Burkina Faso", []string{"XT-"}},
+	{0x09E000, 0x09E3FF, "Sao Tome and Principe", []string{"S9-"}},
+	{0x0A0000, 0x0A7FFF, "Algeria", []string{"7T-"}},
+	{0x0A8000, 0x0A8FFF, "Bahamas", []string{"C6-"}},
+	{0x0AA000, 0x0AA3FF, "Barbados", []string{"8P-"}},
+	{0x0AB000, 0x0AB3FF, "Belize", []string{"V3-"}},
+	{0x0AC000, 0x0ACFFF, "Colombia", []string{"HK-", "HJ-"}},
+	{0x0AE000, 0x0AEFFF, "Costa Rica", []string{"TI-"}},
+	{0x0B0000, 0x0B0FFF, "Cuba", []string{"CU-"}},
+	{0x0B2000, 0x0B2FFF, "El Salvador", []string{"YS-"}},
+	{0x0B4000, 0x0B4FFF, "Guatemala", []string{"TG-"}},
+	{0x0B6000, 0x0B6FFF, "Guyana", []string{"8R-"}},
+	{0x0B8000, 0x0B8FFF, "Haiti", []string{"HH-"}},
+	{0x0BA000, 0x0BAFFF, "Honduras", []string{"HR-"}},
+	{0x0BC000, 0x0BC3FF, "Saint Vincent and the Grenadines", []string{"J8-"}},
+	{0x0BE000, 0x0BEFFF, "Jamaica", []string{"6Y-"}},
+	{0x0C0000, 0x0C0FFF, "Nicaragua", []string{"YN-"}},
+	{0x0C2000, 0x0C2FFF, "Panama", []string{"HP-"}},
+	{0x0C4000, 0x0C4FFF, "Dominican Republic", []string{"HI"}},
+	{0x0C6000, 0x0C6FFF, "Trinidad and Tobago", []string{"9Y-"}},
+	{0x0C8000, 0x0C8FFF, "Suriname", []string{"PZ-"}},
+	{0x0CA000, 0x0CA3FF, "Antigua and Barbuda", []string{"V2-"}},
+	{0x0CC000, 0x0CC3FF, "Grenada", []string{"J3-"}},
+	{0x0D0000, 0x0D7FFF, "Mexico", []string{"XA-", "XB-", "XC-"}},
+	{0x0D8000, 0x0DFFFF, "Venezuela", []string{"YV"}},
+	{0x100000, 0x1FFFFF, "Russia", []string{"RA-", "RF-"}},
+	{0x201000, 0x2013FF, "Namibia", []string{"V5-"}},
+	{0x202000, 0x2023FF, "Eritrea", []string{"E3-"}},
+	{0x300000, 0x33FFFF, "Italy", []string{"I-"}},
+	{0x340000, 0x37FFFF, "Spain", []string{"EC-", "EM-"}},
+	{0x380000, 0x3BFFFF, "France", []string{"F-"}},
+	{0x3C0000, 0x3FFFFF, "Germany", []string{"D-"}},
+	// The United Kingdom block also covers the Crown dependencies and
+	// overseas territories.
+	{0x400000, 0x43FFFF, "United Kingdom", []string{"G-", "M-", "2-", "VP-", "VQ-", "ZJ"}},
+	{0x440000, 0x447FFF, "Austria", []string{"OE-"}},
+	{0x448000, 0x44FFFF, "Belgium", []string{"OO-"}},
+	{0x450000, 0x457FFF, "Bulgaria", []string{"LZ-"}},
+	{0x458000, 0x45FFFF, "Denmark", []string{"OY-"}},
+	{0x460000, 0x467FFF, "Finland", []string{"OH-"}},
+	{0x468000, 0x46FFFF, "Greece", []string{"SX-"}},
+	{0x470000, 0x477FFF, "Hungary", []string{"HA-"}},
+	{0x478000, 0x47FFFF, "Norway", []string{"LN-"}},
+	{0x480000, 0x487FFF, "Netherlands", []string{"PH-"}},
+	{0x488000, 0x48FFFF, "Poland", []string{"SP-", "SN-"}},
+	{0x490000, 0x497FFF, "Portugal", []string{"CS-", "CR-"}},
+	{0x498000, 0x49FFFF, "Czech Republic", []string{"OK-"}},
+	{0x4A0000, 0x4A7FFF, "Romania", []string{"YR-"}},
+	{0x4A8000, 0x4AFFFF, "Sweden", []string{"SE-"}},
+	{0x4B0000, 0x4B7FFF, "Switzerland", []string{"HB-"}},
+	{0x4B8000, 0x4BFFFF, "Turkey", []string{"TC-"}},
+	{0x4C0000, 0x4C7FFF, "Serbia", []string{"YU-"}},
+	{0x4C8000, 0x4C83FF, "Cyprus", []string{"5B-"}},
+	{0x4CA000, 0x4CAFFF, "Ireland", []string{"EI-", "EJ-"}},
+	{0x4CC000, 0x4CCFFF, "Iceland", []string{"TF-"}},
+	{0x4D0000, 0x4D03FF, "Luxembourg", []string{"LX-"}},
+	{0x4D2000, 0x4D23FF, "Malta", []string{"9H-"}},
+	{0x4D4000, 0x4D43FF, "Monaco", []string{"3A-"}},
+	{0x500000, 0x5003FF, "San Marino", []string{"T7-"}},
+	{0x501000, 0x5013FF, "Albania", []string{"ZA-"}},
+	{0x501C00, 0x501FFF, "Croatia", []string{"9A-"}},
+	{0x502C00, 0x502FFF, "Latvia", []string{"YL-"}},
+	{0x503C00, 0x503FFF, "Lithuania", []string{"LY-"}},
+	{0x504C00, 0x504FFF, "Moldova", []string{"ER-"}},
+	{0x505C00, 0x505FFF, "Slovakia", []string{"OM-"}},
+	{0x506C00, 0x506FFF, "Slovenia", []string{"S5-"}},
+	{0x507C00, 0x507FFF, "Uzbekistan", []string{"UK"}},
+	{0x508000, 0x50FFFF, "Ukraine", []string{"UR-"}},
+	{0x510000, 0x5103FF, "Belarus", []string{"EW-"}},
+	{0x511000, 0x5113FF, "Estonia", []string{"ES-"}},
+	{0x512000, 0x5123FF, "North Macedonia", []string{"Z3-"}},
+	{0x513000, 0x5133FF, "Bosnia and Herzegovina", []string{"E7-"}},
+	{0x514000, 0x5143FF, "Georgia", []string{"4L-"}},
+	{0x515000, 0x5153FF, "Tajikistan", []string{"EY-"}},
+	{0x516000, 0x5163FF, "Montenegro", []string{"4O-"}},
+	{0x600000, 0x6003FF, "Armenia", []string{"EK-"}},
+	{0x600800, 0x600BFF, "Azerbaijan", []string{"4K-"}},
+	{0x601000, 0x6013FF, "Kyrgyzstan", []string{"EX-"}},
+	{0x601800, 0x601BFF, "Turkmenistan", []string{"EZ-"}},
+	{0x680000, 0x6803FF, "Bhutan", []string{"A5-"}},
+	{0x681000, 0x6813FF, "Micronesia", []string{"V6-"}},
+	{0x682000, 0x6823FF, "Mongolia", []string{"JU-"}},
+	{0x683000, 0x6833FF, "Kazakhstan", []string{"UP-"}},
+	{0x684000, 0x6843FF, "Palau", []string{"T8A"}},
+	{0x700000, 0x700FFF, "Afghanistan", []string{"YA-"}},
+	{0x702000, 0x702FFF, "Bangladesh", []string{"S2-"}},
+	{0x704000, 0x704FFF, "Myanmar", []string{"XY-", "XZ-"}},
+	{0x706000, 0x706FFF, "Kuwait", []string{"9K-"}},
+	{0x708000, 0x708FFF, "Laos", []string{"RDPL-"}},
+	{0x70A000, 0x70AFFF, "Nepal", []string{"9N-"}},
+	{0x70C000, 0x70C3FF, "Oman", []string{"A4O-"}},
+	{0x70E000, 0x70EFFF, "Cambodia", []string{"XU-"}},
+	{0x710000, 0x717FFF, "Saudi Arabia", []string{"HZ-"}},
+	{0x718000, 0x71FFFF, "South Korea", []string{"HL"}},
+	{0x720000, 0x727FFF, "North Korea", []string{"P-"}},
+	{0x728000, 0x72FFFF, "Iraq", []string{"YI-"}},
+	{0x730000, 0x737FFF, "Iran", []string{"EP-"}},
+	{0x738000, 0x73FFFF, "Israel", []string{"4X-"}},
+	{0x740000, 0x747FFF, "Jordan", []string{"JY-"}},
+	{0x748000, 0x74FFFF, "Lebanon", []string{"OD-"}},
+	{0x750000, 0x757FFF, "Malaysia", []string{"9M-"}},
+	{0x758000, 0x75FFFF, "Philippines", []string{"RP-"}},
+	{0x760000, 0x767FFF, "Pakistan", []string{"AP-"}},
+	{0x768000, 0x76FFFF, "Singapore", []string{"9V-"}},
+	{0x770000, 0x777FFF, "Sri Lanka", []string{"4R-"}},
+	{0x778000, 0x77FFFF, "Syria", []string{"YK-"}},
+	// China's block also covers Hong Kong (B-H) and Macau (B-M).
+	{0x780000, 0x7BFFFF, "China", []string{"B-"}},
+	{0x7C0000, 0x7FFFFF, "Australia", []string{"VH-"}},
+	{0x800000, 0x83FFFF, "India", []string{"VT-"}},
+	{0x840000, 0x87FFFF, "Japan", []string{"JA"}},
+	{0x880000, 0x887FFF, "Thailand", []string{"HS-"}},
+	{0x888000, 0x88FFFF, "Vietnam", []string{"VN-"}},
+	{0x890000, 0x890FFF, "Yemen", []string{"7O-"}},
+	{0x894000, 0x894FFF, "Bahrain", []string{"A9C-"}},
+	{0x895000, 0x8953FF, "Brunei", []string{"V8-"}},
+	{0x896000, 0x896FFF, "United Arab Emirates", []string{"A6-"}},
+	{0x897000, 0x8973FF, "Solomon Islands", []string{"H4-"}},
+	{0x898000, 0x898FFF, "Papua New Guinea", []string{"P2-"}},
+	{0x899000, 0x8993FF, "Taiwan", []string{"B-"}},
+	{0x8A0000, 0x8A7FFF, "Indonesia", []string{"PK-"}},
+	{0x900000, 0x9003FF, "Marshall Islands", []string{"V7-"}},
+	{0x901000, 0x9013FF, "Cook Islands", []string{"E5-"}},
+	{0x902000, 0x9023FF, "Samoa", []string{"5W-"}},
+	{0xA00000, 0xAFFFFF, "United States", []string{"N"}},
+	{0xC00000, 0xC3FFFF, "Canada", []string{"C-"}},
+	{0xC80000, 0xC87FFF, "New Zealand", []string{"ZK-"}},
+	{0xC88000, 0xC88FFF, "Fiji", []string{"DQ-"}},
+	{0xC8A000, 0xC8A3FF, "Nauru", []string{"C2-"}},
+	{0xC8C000, 0xC8C3FF, "Saint Lucia", []string{"J6-"}},
+	{0xC8D000, 0xC8D3FF, "Tonga", []string{"A3-"}},
+	{0xC8E000, 0xC8E3FF, "Kiribati", []string{"T3-"}},
+	{0xC90000, 0xC903FF, "Vanuatu", []string{"YJ-"}},
+	{0xE00000, 0xE3FFFF, "Argentina", []string{"LV-", "LQ-"}},
+	{0xE40000, 0xE7FFFF, "Brazil", []string{"PP-", "PR-", "PS-", "PT-", "PU-"}},
+	{0xE80000, 0xE80FFF, "Chile", []string{"CC-"}},
+	{0xE84000, 0xE84FFF, "Ecuador", []string{"HC-"}},
+	{0xE88000, 0xE88FFF, "Paraguay", []string{"ZP-"}},
+	{0xE8C000, 0xE8CFFF, "Peru", []string{"OB-"}},
+	{0xE90000, 0xE90FFF, "Uruguay", []string{"CX-"}},
+	{0xE94000, 0xE94FFF, "Bolivia", []string{"CP-"}},
+	{0xF00000, 0xF07FFF, "ICAO (temporary)", nil},
+	{0xF09000, 0xF093FF, "ICAO (special use)", nil},
+}
+
+// military are the parts of national blocks known to be used by armed
+// forces, ordered by Start. Prefixes are not used.
+var military = []Allocation{
+	{0x010070, 0x01008F, "Egypt", nil},
+	{0x0A4000, 0x0A4FFF, "Algeria", nil},
+	{0x33FF00, 0x33FFFF, "Italy", nil},
+	{0x350000, 0x37FFFF, "Spain", nil},
+	{0x3AA000, 0x3AFFFF, "France", nil},
+	{0x3B7000, 0x3BFFFF, "France", nil},
+	{0x3EA000, 0x3EBFFF, "Germany", nil},
+	{0x3F4000, 0x3FBFFF, "Germany", nil},
+	{0x400000, 0x40003F, "United Kingdom", nil},
+	{0x43C000, 0x43CFFF, "United Kingdom", nil},
+	{0x444000, 0x446FFF, "Austria", nil},
+	{0x44F000, 0x44FFFF, "Belgium", nil},
+	{0x457000, 0x457FFF, "Bulgaria", nil},
+	{0x45F400, 0x45F4FF, "Denmark", nil},
+	{0x468000, 0x4683FF, "Greece", nil},
+	{0x473C00, 0x473C0F, "Hungary", nil},
+	{0x478100, 0x4781FF, "Norway", nil},
+	{0x480000, 0x480FFF, "Netherlands", nil},
+	{0x48D800, 0x48D87F, "Poland", nil},
+	{0x497C00, 0x497CFF, "Portugal", nil},
+	{0x498420, 0x49842F, "Czech Republic", nil},
+	{0x4B7000, 0x4B7FFF, "Switzerland", nil},
+	{0x4B8200, 0x4B82FF, "Turkey", nil},
+	{0x506F00, 0x506FFF, "Slovenia", nil},
+	{0x70C070, 0x70C07F, "Oman", nil},
+	{0x710258, 0x71028F, "Saudi Arabia", nil},
+	{0x710380, 0x71039F, "Saudi Arabia", nil},
+	{0x738A00, 0x738AFF, "Israel", nil},
+	{0x7CF800, 0x7CFAFF, "Australia", nil},
+	{0x800200, 0x8002FF, "India", nil},
+	// Past the last N-number.
+	{0xADF7C8, 0xAFFFFF, "United States", nil},
+	{0xC20000, 0xC3FFFF, "Canada", nil},
+	{0xE06000, 0xE06FFF, "Argentina", nil},
+	{0xE40000, 0xE41FFF, "Brazil", nil},
+}
@@ -0,0 +1,168 @@
+// Package model is the source independent schema every sink, exporter and
+// analysis works against. Sources convert their wire types into it; see
+// flightRadar's Canonical methods for FR24.
+package model
+
+import (
+	"time"
+
+	"radar/units"
+)
+
+const (
+	SourceFR24          = "fr24"
+	SourceMarineTraffic = "marinetraffic"
+)
+
+// Position is a single report of where an aircraft was. Altitude,
+// GroundSpeed and VerticalRate are as reported, in feet, knots and feet per
+// minute; the SI fields next to them are filled by Normalize.
+type Position struct {
+	Time            time.Time `json:"time"`
+	Lat             float64   `json:"lat"`
+	Lon             float64   `json:"lon"`
+	Altitude        int       `json:"altitude"`
+	AltitudeM       float64   `json:"altitude_m"`
+	GroundSpeed     int       `json:"ground_speed"`
+	GroundSpeedMps  float64   `json:"ground_speed_mps"`
+	Track           int       `json:"track"`
+	VerticalRate    int       `json:"vertical_rate,omitempty"`
+	VerticalRateMps float64   `json:"vertical_rate_mps,omitempty"`
+	OnGround        bool      `json:"on_ground,omitempty"`
+	Squawk          string    `json:"squawk,omitempty"`
+	// Phase is set by package phase.
+	Phase string `json:"phase,omitempty"`
+}
+
+// Normalize derives the SI fields from the reported ones.
+func (p *Position) Normalize() {
+	p.AltitudeM = units.Round(units.FeetToMeters(float64(p.Altitude)), 1)
+	p.GroundSpeedMps = units.Round(units.KnotsToMps(float64(p.GroundSpeed)), 2)
+	p.VerticalRateMps = units.Round(units.FpmToMps(float64(p.VerticalRate)), 2)
+}
+
+// In renders altitude, ground speed and vertical rate in the given output
+// system. Exporters go through this so every format agrees on units.
+func (p *Position) In(s units.System) (altitude, speed, verticalRate units.Value) {
+	return s.Altitude(p.AltitudeM), s.Speed(p.GroundSpeedMps), s.VerticalRate(p.VerticalRateMps)
+}
+
+// Photo sizes, as FR24 lists JetPhotos images.
+const (
+	PhotoThumbnail = "thumbnail"
+	PhotoMedium    = "medium"
+	PhotoLarge     = "large"
+)
+
+// Photo is one size of an aircraft photo. The sizes of one photo share its
+// Link.
+type Photo struct {
+	URL          string `json:"url"`
+	Size         string `json:"size,omitempty"`
+	Link         string `json:"link,omitempty"`
+	Photographer string `json:"photographer,omitempty"`
+	Source       string `json:"source,omitempty"`
+}
+
+type Aircraft struct {
+	Hex          string  `json:"hex,omitempty"`
+	Registration string  `json:"registration,omitempty"`
+	TypeCode     string  `json:"type_code,omitempty"`
+	TypeName     string  `json:"type_name,omitempty"`
+	Msn          string  `json:"msn,omitempty"`
+	CountryID    int     `json:"country_id,omitempty"`
+	Photos       []Photo `json:"photos,omitempty"`
+}
+
+type Airport struct {
+	Iata        string  `json:"iata,omitempty"`
+	Icao        string  `json:"icao,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	Lat         float64 `json:"lat"`
+	Lon         float64 `json:"lon"`
+	Elevation   int     `json:"elevation,omitempty"`
+	City        string  `json:"city,omitempty"`
+	Country     string  `json:"country,omitempty"`
+	CountryCode string  `json:"country_code,omitempty"`
+	Timezone    string  `json:"timezone,omitempty"`
+	UTCOffset   int     `json:"utc_offset,omitempty"`
+}
+
+// Code returns the ICAO code, or the IATA code when there is none.
+func (a *Airport) Code() string {
+	if a.Icao != "" {
+		return a.Icao
+	}
+	return a.Iata
+}
+
+type Operator struct {
+	Name string `json:"name,omitempty"`
+	Iata string `json:"iata,omitempty"`
+	Icao string `json:"icao,omitempty"`
+}
+
+// Times are nil when the source did not report them.
+type Times struct {
+	ScheduledDeparture *time.Time `json:"scheduled_departure,omitempty"`
+	ScheduledArrival   *time.Time `json:"scheduled_arrival,omitempty"`
+	EstimatedDeparture *time.Time `json:"estimated_departure,omitempty"`
+	EstimatedArrival   *time.Time `json:"estimated_arrival,omitempty"`
+	ActualDeparture    *time.Time `json:"actual_departure,omitempty"`
+	ActualArrival      *time.Time `json:"actual_arrival,omitempty"`
+	Eta                *time.Time `json:"eta,omitempty"`
+}
+
+// Flight is one flight of one aircraft. Trail is ordered oldest first and
+// Position is the latest known report.
+type Flight struct {
+	Source      string    `json:"source"`
+	ID          string    `json:"id"`
+	Callsign    string    `json:"callsign,omitempty"`
+	Number      string    `json:"number,omitempty"`
+	Aircraft    Aircraft  `json:"aircraft"`
+	Airline     *Operator `json:"airline,omitempty"`
+	Owner       *Operator `json:"owner,omitempty"`
+	Origin      *Airport  `json:"origin,omitempty"`
+	Destination *Airport  `json:"destination,omitempty"`
+	// Diverted is where the flight actually went when it differs from
+	// Destination.
+	Diverted *Airport `json:"diverted,omitempty"`
+	Status   string   `json:"status,omitempty"`
+	// Class is military, government, state, medical or civil, see package
+	// classify.
+	Class    string     `json:"class,omitempty"`
+	Live     bool       `json:"live"`
+	Times    Times      `json:"times"`
+	Delays   Delays     `json:"delays"`
+	Position *Position  `json:"position,omitempty"`
+	Trail    []Position `json:"trail,omitempty"`
+	Updated  time.Time  `json:"updated"`
+}
+
+type VesselPosition struct {
+	Time    time.Time `json:"time"`
+	Lat     float64   `json:"lat"`
+	Lon     float64   `json:"lon"`
+	Speed   float64   `json:"speed"`
+	Course  float64   `json:"course"`
+	Heading int       `json:"heading,omitempty"`
+}
+
+// Vessel is a ship as reported by AIS sources such as MarineTraffic.
+type Vessel struct {
+	Source      string          `json:"source"`
+	ID          string          `json:"id"`
+	Mmsi        string          `json:"mmsi,omitempty"`
+	Imo         string          `json:"imo,omitempty"`
+	Name        string          `json:"name,omitempty"`
+	Callsign    string          `json:"callsign,omitempty"`
+	Type        string          `json:"type,omitempty"`
+	Flag        string          `json:"flag,omitempty"`
+	Length      float64         `json:"length,omitempty"`
+	Width       float64         `json:"width,omitempty"`
+	Destination string          `json:"destination,omitempty"`
+	Eta         *time.Time      `json:"eta,omitempty"`
+	Position    *VesselPosition `json:"position,omitempty"`
+	Updated     time.Time       `json:"updated"`
+}
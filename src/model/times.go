@@ -0,0 +1,104 @@
+package model
+
+import "time"
+
+// Delays are in seconds, negative when early. They are nil when either side
+// of the comparison is unknown.
+type Delays struct {
+	Departure *int64 `json:"departure,omitempty"`
+	Arrival   *int64 `json:"arrival,omitempty"`
+	// DepartureEstimated and ArrivalEstimated are set when the delay is
+	// based on an estimate rather than an actual time.
+	DepartureEstimated bool `json:"departure_estimated,omitempty"`
+	ArrivalEstimated   bool `json:"arrival_estimated,omitempty"`
+}
+
+// Delays compares actual (or else estimated) times with the schedule.
+func (t *Times) Delays() Delays {
+	var d Delays
+	if t.ScheduledDeparture != nil {
+		if t.ActualDeparture != nil {
+			d.Departure = seconds(t.ActualDeparture.Sub(*t.ScheduledDeparture))
+		} else if t.EstimatedDeparture != nil {
+			d.Departure = seconds(t.EstimatedDeparture.Sub(*t.ScheduledDeparture))
+			d.DepartureEstimated = true
+		}
+	}
+	if t.ScheduledArrival != nil {
+		switch {
+		case t.ActualArrival != nil:
+			d.Arrival = seconds(t.ActualArrival.Sub(*t.ScheduledArrival))
+		case t.EstimatedArrival != nil:
+			d.Arrival = seconds(t.EstimatedArrival.Sub(*t.ScheduledArrival))
+			d.ArrivalEstimated = true
+		case t.Eta != nil:
+			d.Arrival = seconds(t.Eta.Sub(*t.ScheduledArrival))
+			d.ArrivalEstimated = true
+		}
+	}
+	return d
+}
+
+func seconds(d time.Duration) *int64 {
+	s := int64(d / time.Second)
+	return &s
+}
+
+// Location is the airport's time zone. The IANA name is preferred so DST is
+// handled; the last reported offset is used when the name is unknown to the
+// local tz database.
+func (a *Airport) Location() *time.Location {
+	if a == nil {
+		return time.UTC
+	}
+	if a.Timezone != "" {
+		if loc, err := time.LoadLocation(a.Timezone); err == nil {
+			return loc
+		}
+	}
+	if a.UTCOffset != 0 {
+		return time.FixedZone(a.Timezone, a.UTCOffset)
+	}
+	return time.UTC
+}
+
+// LocalTimes are RFC 3339 strings in the local time of the airport they
+// refer to: departures at the origin, arrivals at the destination.
+type LocalTimes struct {
+	ScheduledDeparture string `json:"scheduled_departure,omitempty"`
+	ScheduledArrival   string `json:"scheduled_arrival,omitempty"`
+	EstimatedDeparture string `json:"estimated_departure,omitempty"`
+	EstimatedArrival   string `json:"estimated_arrival,omitempty"`
+	ActualDeparture    string `json:"actual_departure,omitempty"`
+	ActualArrival      string `json:"actual_arrival,omitempty"`
+	Eta                string `json:"eta,omitempty"`
+}
+
+// ArrivalAirport is where the flight lands: the diversion airport if any.
+func (f *Flight) ArrivalAirport() *Airport {
+	if f.Diverted != nil {
+		return f.Diverted
+	}
+	return f.Destination
+}
+
+func (f *Flight) LocalTimes() LocalTimes {
+	dep := f.Origin.Location()
+	arr := f.ArrivalAirport().Location()
+	return LocalTimes{
+		ScheduledDeparture: local(f.Times.ScheduledDeparture, dep),
+		ScheduledArrival:   local(f.Times.ScheduledArrival, arr),
+		EstimatedDeparture: local(f.Times.EstimatedDeparture, dep),
+		EstimatedArrival:   local(f.Times.EstimatedArrival, arr),
+		ActualDeparture:    local(f.Times.ActualDeparture, dep),
+		ActualArrival:      local(f.Times.ActualArrival, arr),
+		Eta:                local(f.Times.Eta, arr),
+	}
+}
+
+func local(t *time.Time, loc *time.Location) string {
+	if t == nil {
+		return ""
+	}
+	return t.In(loc).Format(time.RFC3339)
+}
@@ -0,0 +1,192 @@
+// Package movement infers departures and arrivals from ground/air
+// transitions and matches them to the nearest airport, so flights without
+// schedule data (general aviation, military) get real times too.
+package movement
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"radar/geo"
+	"radar/model"
+)
+
+const (
+	Departure = "departure"
+	Arrival   = "arrival"
+)
+
+// MatchRadius is how far from an airport's reference point, in meters, a
+// transition is still attributed to it.
+const MatchRadius = 8000
+
+// sameWithin treats transitions of one flight this close in time as the
+// same movement seen by both the feed and a trail.
+const sameWithin = 10 * time.Minute
+
+// Airports finds the airport nearest to a point, e.g. reference.Directory.
+type Airports interface {
+	Nearest(p geo.Point, radius float64) (*model.Airport, float64, bool)
+}
+
+type Event struct {
+	Type         string `json:"type"`
+	FlightID     string `json:"flight_id"`
+	Callsign     string `json:"callsign,omitempty"`
+	Registration string `json:"registration,omitempty"`
+	Hex          string `json:"hex,omitempty"`
+	// Time is the first position in the new state: liftoff for a departure,
+	// touchdown for an arrival, to within the spacing of the positions.
+	Time time.Time `json:"time"`
+	// Airport is nil when no known airport is within MatchRadius.
+	Airport   *model.Airport `json:"airport,omitempty"`
+	DistanceM float64        `json:"distance_m,omitempty"`
+	Position  model.Position `json:"position"`
+}
+
+func (e Event) String() string {
+	airport := "unknown airport"
+	if e.Airport != nil {
+		airport = e.Airport.Code()
+	}
+	return fmt.Sprintf("%s %s %s at %s %s", e.Type, e.Callsign, e.FlightID, airport, e.Time.Format(time.RFC3339))
+}
+
+// Trail returns every transition in f's trail (oldest first).
+func Trail(f *model.Flight, airports Airports) []Event {
+	var events []Event
+	for i := 1; i < len(f.Trail); i++ {
+		if e := transition(f, f.Trail[i-1], f.Trail[i], airports); e != nil {
+			events = append(events, *e)
+		}
+	}
+	return events
+}
+
+// transition returns the movement between two consecutive positions, if
+// they differ in being on the ground.
+func transition(f *model.Flight, prev, p model.Position, airports Airports) *Event {
+	if prev.OnGround == p.OnGround {
+		return nil
+	}
+	e := &Event{
+		Type:         Departure,
+		FlightID:     f.ID,
+		Callsign:     f.Callsign,
+		Registration: f.Aircraft.Registration,
+		Hex:          f.Aircraft.Hex,
+		Time:         p.Time,
+		Position:     p,
+	}
+	// The ground position is the one nearest the runway.
+	ground := prev
+	if p.OnGround {
+		e.Type = Arrival
+		ground = p
+	}
+	if airports != nil {
+		if a, dist, ok := airports.Nearest(geo.Point{Lat: ground.Lat, Lon: ground.Lon}, MatchRadius); ok {
+			e.Airport, e.DistanceM = a, float64(int(dist))
+		}
+	}
+	return e
+}
+
+// Tracker follows flights across feed snapshots and reports each movement
+// once, whether it is first seen in the feed or in a trail.
+type Tracker struct {
+	airports Airports
+
+	mu      sync.Mutex
+	flights map[string]*state
+}
+
+type state struct {
+	last     model.Position
+	seen     time.Time
+	reported []Event
+}
+
+func NewTracker(airports Airports) *Tracker {
+	return &Tracker{airports: airports, flights: make(map[string]*state)}
+}
+
+// Feed compares a flight's feed position with the one before it.
+func (t *Tracker) Feed(f *model.Flight) []Event {
+	if f.Position == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.flights[f.ID]
+	if !ok {
+		t.flights[f.ID] = &state{last: *f.Position, seen: time.Now()}
+		return nil
+	}
+	s.seen = time.Now()
+	if !f.Position.Time.After(s.last.Time) {
+		return nil
+	}
+	prev := s.last
+	s.last = *f.Position
+	if e := transition(f, prev, *f.Position, t.airports); e != nil {
+		return s.report([]Event{*e})
+	}
+	return nil
+}
+
+// Trail checks a flight's accumulated trail, returning all its movements
+// and the ones not reported before.
+func (t *Tracker) Trail(f *model.Flight) (all, fresh []Event) {
+	all = Trail(f, t.airports)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.flights[f.ID]
+	if !ok {
+		s = &state{}
+		if n := len(f.Trail); n > 0 {
+			s.last = f.Trail[n-1]
+		}
+		t.flights[f.ID] = s
+	}
+	s.seen = time.Now()
+	return all, s.report(all)
+}
+
+// report keeps the events not already reported. The caller holds the lock.
+func (s *state) report(events []Event) []Event {
+	var fresh []Event
+	for _, e := range events {
+		known := false
+		for _, r := range s.reported {
+			if r.Type == e.Type && absDuration(r.Time.Sub(e.Time)) < sameWithin {
+				known = true
+				break
+			}
+		}
+		if !known {
+			s.reported = append(s.reported, e)
+			fresh = append(fresh, e)
+		}
+	}
+	return fresh
+}
+
+// Expire forgets flights not seen for maxAge.
+func (t *Tracker) Expire(maxAge time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, s := range t.flights {
+		if time.Since(s.seen) > maxAge {
+			delete(t.flights, id)
+		}
+	}
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
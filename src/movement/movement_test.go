@@ -0,0 +1,126 @@
+package movement
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"radar/geo"
+	"radar/model"
+)
+
+// airports is a linear Airports for tests.
+type airports []*model.Airport
+
+func (as airports) Nearest(p geo.Point, radius float64) (*model.Airport, float64, bool) {
+	var best *model.Airport
+	for _, a := range as {
+		if d := geo.Distance(p, geo.Point{Lat: a.Lat, Lon: a.Lon}); d <= radius {
+			best, radius = a, d
+		}
+	}
+	return best, radius, best != nil
+}
+
+var (
+	directory = airports{
+		{Icao: "EDDF", Lat: 50.033, Lon: 8.570},
+		{Icao: "EDDM", Lat: 48.354, Lon: 11.786},
+	}
+	start = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+)
+
+// stop is a position a number of minutes after start; altitude 0 is on the
+// ground.
+type stop struct {
+	minute   int
+	lat, lon float64
+	altitude int
+}
+
+func positions(stops ...stop) []model.Position {
+	trail := make([]model.Position, len(stops))
+	for i, s := range stops {
+		trail[i] = model.Position{
+			Time:     start.Add(time.Duration(s.minute) * time.Minute),
+			Lat:      s.lat,
+			Lon:      s.lon,
+			Altitude: s.altitude,
+			OnGround: s.altitude == 0,
+		}
+	}
+	return trail
+}
+
+// describe lists events as "type airport minute".
+func describe(events []Event) []string {
+	var out []string
+	for _, e := range events {
+		airport := "unknown"
+		if e.Airport != nil {
+			airport = e.Airport.Code()
+		}
+		out = append(out, e.Type+" "+airport+" "+e.Time.Sub(start).String())
+	}
+	return out
+}
+
+func TestTrail(t *testing.T) {
+	tests := []struct {
+		name  string
+		trail []model.Position
+		want  []string
+	}{
+		{"ground only", positions(stop{0, 50.03, 8.57, 0}, stop{1, 50.03, 8.58, 0}), nil},
+		{"departure", positions(stop{0, 50.03, 8.57, 0}, stop{1, 50.04, 8.55, 1200}, stop{2, 50.1, 8.4, 5000}),
+			[]string{"departure EDDF 1m0s"}},
+		{"flight", positions(stop{0, 50.03, 8.57, 0}, stop{1, 50.04, 8.55, 1200}, stop{50, 48.36, 11.75, 1500}, stop{51, 48.354, 11.786, 0}),
+			[]string{"departure EDDF 1m0s", "arrival EDDM 51m0s"}},
+		{"away from any airport", positions(stop{0, 49, 10, 0}, stop{1, 49.01, 10, 500}),
+			[]string{"departure unknown 1m0s"}},
+		{"touch and go", positions(stop{0, 50.1, 8.4, 2000}, stop{3, 50.033, 8.57, 0}, stop{4, 50.03, 8.55, 800}),
+			[]string{"arrival EDDF 3m0s", "departure EDDF 4m0s"}},
+	}
+	for _, tt := range tests {
+		f := &model.Flight{ID: "f", Trail: tt.trail}
+		if got := describe(Trail(f, directory)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTracker(t *testing.T) {
+	steps := []struct {
+		name  string
+		feed  *stop
+		trail []model.Position
+		want  []string
+	}{
+		{"first position", &stop{0, 50.03, 8.57, 0}, nil, nil},
+		{"still on the ground", &stop{1, 50.03, 8.58, 0}, nil, nil},
+		{"liftoff", &stop{2, 50.04, 8.55, 1200}, nil, []string{"departure EDDF 2m0s"}},
+		{"older position", &stop{1, 50.03, 8.58, 0}, nil, nil},
+		{"trail with the same departure", nil,
+			positions(stop{0, 50.03, 8.57, 0}, stop{1, 50.033, 8.57, 0}, stop{2, 50.04, 8.55, 1200}), nil},
+		{"cruise", &stop{30, 49, 10, 30000}, nil, nil},
+		{"trail with the arrival", nil,
+			positions(stop{2, 50.04, 8.55, 1200}, stop{50, 48.36, 11.75, 1500}, stop{51, 48.354, 11.786, 0}),
+			[]string{"arrival EDDM 51m0s"}},
+		{"touchdown already reported", &stop{51, 48.354, 11.786, 0}, nil, nil},
+	}
+	tracker := NewTracker(directory)
+	for _, step := range steps {
+		f := &model.Flight{ID: "f"}
+		var events []Event
+		if step.feed != nil {
+			f.Position = &positions(*step.feed)[0]
+			events = tracker.Feed(f)
+		} else {
+			f.Trail = step.trail
+			_, events = tracker.Trail(f)
+		}
+		if got := describe(events); !slices.Equal(got, step.want) {
+			t.Errorf("%s: %v, want %v", step.name, got, step.want)
+		}
+	}
+}
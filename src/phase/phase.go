@@ -0,0 +1,195 @@
+// Package phase splits a flight's trail into flight phases from altitude,
+// ground speed and vertical rate.
+package phase
+
+import (
+	"time"
+
+	"radar/model"
+)
+
+const (
+	// Ground covers taxiing and standing.
+	Ground   = "ground"
+	Takeoff  = "takeoff"
+	Climb    = "climb"
+	Cruise   = "cruise"
+	Descent  = "descent"
+	Approach = "approach"
+	Landing  = "landing"
+)
+
+// Thresholds. Heights are in feet above the departure or arrival airport
+// when its elevation is known, else above sea level.
+const (
+	// rollSpeed separates taxiing from a takeoff or landing roll, knots.
+	rollSpeed = 40
+	// levelRate is the vertical rate below which flight is level, ft/min.
+	levelRate = 300
+	// takeoffHeight ends the takeoff phase, landingHeight starts landing.
+	takeoffHeight  = 1000
+	landingHeight  = 200
+	approachHeight = 3000
+	// cruiseShare is the share of the highest altitude level flight must
+	// reach to count as cruise rather than a level-off.
+	cruiseShare = 0.7
+	// rateWindow is how far either side of a position the vertical rate is
+	// measured over.
+	rateWindow = 30 * time.Second
+	// minSegment is the shortest climb, cruise or descent kept; shorter ones
+	// are merged into the phase before them.
+	minSegment = time.Minute
+)
+
+// Segment is a stretch of the trail in one phase.
+type Segment struct {
+	Phase string    `json:"phase"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Result holds the phase transitions of a flight. Takeoff and Landing are
+// the first liftoff and the last touchdown seen in the trail, for when the
+// source has no actual times.
+type Result struct {
+	Segments []Segment  `json:"segments,omitempty"`
+	Takeoff  *time.Time `json:"takeoff,omitempty"`
+	Landing  *time.Time `json:"landing,omitempty"`
+}
+
+// Flight labels every position of f.Trail (oldest first) with its phase
+// and returns the segments.
+func Flight(f *model.Flight) Result {
+	trail := f.Trail
+	if len(trail) == 0 {
+		return Result{}
+	}
+
+	top, maxAlt := 0, 0
+	for i, p := range trail {
+		if p.Altitude > maxAlt {
+			top, maxAlt = i, p.Altitude
+		}
+	}
+	var depElev, arrElev int
+	if f.Origin != nil {
+		depElev = f.Origin.Elevation
+	}
+	if a := f.ArrivalAirport(); a != nil {
+		arrElev = a.Elevation
+	}
+
+	labels := make([]string, len(trail))
+	for i, p := range trail {
+		if p.OnGround {
+			labels[i] = Ground
+			continue
+		}
+		height := p.Altitude - depElev
+		if i > top {
+			height = p.Altitude - arrElev
+		}
+		vr := verticalRate(trail, i)
+		switch {
+		case vr > levelRate && i <= top && height < takeoffHeight:
+			labels[i] = Takeoff
+		case vr > levelRate:
+			labels[i] = Climb
+		case vr < -levelRate && height < landingHeight:
+			labels[i] = Landing
+		case vr >= -levelRate && vr <= levelRate && float64(p.Altitude) >= cruiseShare*float64(maxAlt):
+			labels[i] = Cruise
+		case height < approachHeight && i > top:
+			labels[i] = Approach
+		case vr < -levelRate || i > top:
+			labels[i] = Descent
+		default:
+			labels[i] = Climb
+		}
+	}
+	rolls(trail, labels)
+	smooth(trail, labels)
+
+	var r Result
+	for i := range trail {
+		trail[i].Phase = labels[i]
+		if i == 0 || labels[i] != labels[i-1] {
+			r.Segments = append(r.Segments, Segment{Phase: labels[i], Start: trail[i].Time})
+		}
+		r.Segments[len(r.Segments)-1].End = trail[i].Time
+		if i == 0 {
+			continue
+		}
+		if trail[i-1].OnGround && !trail[i].OnGround && r.Takeoff == nil {
+			t := trail[i].Time
+			r.Takeoff = &t
+		}
+		if !trail[i-1].OnGround && trail[i].OnGround {
+			t := trail[i].Time
+			r.Landing = &t
+		}
+	}
+	return r
+}
+
+// rolls labels fast ground movement as a takeoff roll when the aircraft is
+// about to lift off and as a landing roll when it has just touched down.
+func rolls(trail []model.Position, labels []string) {
+	airborneBefore := false
+	for i, p := range trail {
+		if !p.OnGround {
+			airborneBefore = true
+			continue
+		}
+		if p.GroundSpeed < rollSpeed {
+			airborneBefore = false
+			continue
+		}
+		if airborneBefore {
+			labels[i] = Landing
+		} else {
+			labels[i] = Takeoff
+		}
+	}
+}
+
+// smooth merges climb, cruise and descent segments shorter than minSegment
+// into the segment before them, so single noisy positions do not flip the
+// phase.
+func smooth(trail []model.Position, labels []string) {
+	start := 0
+	for i := 1; i <= len(trail); i++ {
+		if i < len(trail) && labels[i] == labels[start] {
+			continue
+		}
+		switch labels[start] {
+		case Climb, Cruise, Descent:
+			if start > 0 && trail[i-1].Time.Sub(trail[start].Time) < minSegment && i < len(trail) {
+				for j := start; j < i; j++ {
+					labels[j] = labels[start-1]
+				}
+			}
+		}
+		start = i
+	}
+}
+
+// verticalRate is the reported rate, or else the altitude change across
+// rateWindow either side of position i, in ft/min.
+func verticalRate(trail []model.Position, i int) float64 {
+	if trail[i].VerticalRate != 0 {
+		return float64(trail[i].VerticalRate)
+	}
+	from, to := i, i
+	for from > 0 && trail[i].Time.Sub(trail[from].Time) < rateWindow {
+		from--
+	}
+	for to < len(trail)-1 && trail[to].Time.Sub(trail[i].Time) < rateWindow {
+		to++
+	}
+	dt := trail[to].Time.Sub(trail[from].Time).Minutes()
+	if dt <= 0 {
+		return 0
+	}
+	return float64(trail[to].Altitude-trail[from].Altitude) / dt
+}
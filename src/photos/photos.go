@@ -0,0 +1,255 @@
+// Package photos downloads aircraft photos into a content addressed store
+// and keeps the attribution that comes with them next to each image.
+package photos
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"radar/model"
+)
+
+// Fetcher is satisfied by flightRadar.Client.
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) ([]byte, error)
+}
+
+// Attribution is written as <sha256>.json next to every image.
+type Attribution struct {
+	URL           string    `json:"url"`
+	Size          string    `json:"size,omitempty"`
+	Link          string    `json:"link,omitempty"`
+	Photographer  string    `json:"photographer,omitempty"`
+	Source        string    `json:"source,omitempty"`
+	Registrations []string  `json:"registrations"`
+	SHA256        string    `json:"sha256"`
+	Downloaded    time.Time `json:"downloaded"`
+}
+
+// indexEntry records which photo URLs of a registration are already stored.
+type indexEntry struct {
+	URL    string `json:"url"`
+	Size   string `json:"size,omitempty"`
+	Link   string `json:"link,omitempty"`
+	SHA256 string `json:"sha256"`
+}
+
+// photoKey tells photos apart: every size of a JetPhotos photo shares its
+// link.
+func photoKey(url, link string) string {
+	if link != "" {
+		return link
+	}
+	return url
+}
+
+// Store lays files out as
+//
+//	<Dir>/objects/<aa>/<sha256><ext>   the image
+//	<Dir>/objects/<aa>/<sha256>.json   its Attribution
+//	<Dir>/index/<registration>.json    URLs already stored for an aircraft
+type Store struct {
+	Dir string
+	// Limit is how many photos are kept per registration. Every size of a
+	// kept photo is stored.
+	Limit int
+
+	fetcher Fetcher
+	queue   chan model.Aircraft
+	// locks serialises work per registration, objects guards sidecars that
+	// several registrations may share.
+	locks   sync.Map
+	objects sync.Mutex
+}
+
+func NewStore(dir string, limit int, fetcher Fetcher) *Store {
+	return &Store{Dir: dir, Limit: limit, fetcher: fetcher}
+}
+
+// Start runs workers that download what Enqueue hands them until ctx ends.
+func (s *Store) Start(ctx context.Context, workers int) {
+	s.queue = make(chan model.Aircraft, 256)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case a := <-s.queue:
+					if _, err := s.Save(ctx, a); err != nil {
+						fmt.Println("[Err] Could not save photos of", a.Registration, err)
+					}
+				}
+			}
+		}()
+	}
+}
+
+// Enqueue hands an aircraft to the workers without blocking the sweep. It
+// is dropped when the queue is full; the next sighting will retry.
+func (s *Store) Enqueue(a model.Aircraft) {
+	if s.queue == nil || a.Registration == "" || len(a.Photos) == 0 {
+		return
+	}
+	select {
+	case s.queue <- a:
+	default:
+	}
+}
+
+// Save downloads the aircraft's photos that are not stored yet, every size
+// of up to Limit photos per registration, and returns how many images were
+// added.
+func (s *Store) Save(ctx context.Context, a model.Aircraft) (int, error) {
+	if a.Registration == "" {
+		return 0, nil
+	}
+	lock, _ := s.locks.LoadOrStore(a.Registration, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	index, err := s.readIndex(a.Registration)
+	if err != nil {
+		return 0, err
+	}
+	stored := make(map[string]bool)
+	kept := make(map[string]bool)
+	for _, e := range index {
+		stored[e.URL] = true
+		kept[photoKey(e.URL, e.Link)] = true
+	}
+
+	added := 0
+	for _, photo := range a.Photos {
+		if photo.URL == "" || stored[photo.URL] {
+			continue
+		}
+		key := photoKey(photo.URL, photo.Link)
+		if !kept[key] && len(kept) >= s.Limit {
+			continue
+		}
+		var sum string
+		if sum, err = s.download(ctx, photo, a.Registration); err != nil {
+			break
+		}
+		index = append(index, indexEntry{URL: photo.URL, Size: photo.Size, Link: photo.Link, SHA256: sum})
+		stored[photo.URL] = true
+		kept[key] = true
+		added++
+	}
+	if added > 0 {
+		if werr := s.writeIndex(a.Registration, index); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return added, err
+}
+
+func (s *Store) download(ctx context.Context, photo model.Photo, registration string) (string, error) {
+	body, err := s.fetcher.Fetch(ctx, photo.URL)
+	if err != nil {
+		return "", err
+	}
+	raw := sha256.Sum256(body)
+	sum := hex.EncodeToString(raw[:])
+	dir := filepath.Join(s.Dir, "objects", sum[:2])
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return "", err
+	}
+
+	image := filepath.Join(dir, sum+imageExt(photo.URL))
+	if _, err := os.Stat(image); os.IsNotExist(err) {
+		if err := ioutil.WriteFile(image, body, 0644); err != nil {
+			return "", err
+		}
+	}
+
+	// The same image can be linked from several registrations.
+	s.objects.Lock()
+	defer s.objects.Unlock()
+	sidecar := filepath.Join(dir, sum+".json")
+	attribution := Attribution{
+		URL:          photo.URL,
+		Size:         photo.Size,
+		Link:         photo.Link,
+		Photographer: photo.Photographer,
+		Source:       photo.Source,
+		SHA256:       sum,
+		Downloaded:   time.Now().UTC(),
+	}
+	if existing, err := ioutil.ReadFile(sidecar); err == nil {
+		json.Unmarshal(existing, &attribution)
+	}
+	if !contains(attribution.Registrations, registration) {
+		attribution.Registrations = append(attribution.Registrations, registration)
+	}
+	out, err := json.MarshalIndent(attribution, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return sum, ioutil.WriteFile(sidecar, out, 0644)
+}
+
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+func (s *Store) indexFile(registration string) string {
+	return filepath.Join(s.Dir, "index", unsafeName.ReplaceAllString(registration, "_")+".json")
+}
+
+func (s *Store) readIndex(registration string) ([]indexEntry, error) {
+	body, err := ioutil.ReadFile(s.indexFile(registration))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var index []indexEntry
+	return index, json.Unmarshal(body, &index)
+}
+
+func (s *Store) writeIndex(registration string, index []indexEntry) error {
+	file := s.indexFile(registration)
+	if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+		return err
+	}
+	body, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, body, 0644)
+}
+
+// imageExt takes the extension from the URL path, ignoring the query
+// string JetPhotos appends.
+func imageExt(url string) string {
+	if i := strings.IndexAny(url, "?#"); i >= 0 {
+		url = url[:i]
+	}
+	ext := strings.ToLower(path.Ext(url))
+	switch ext {
+	case ".jpg", ".jpeg", ".png", ".webp", ".gif":
+		return ext
+	}
+	return ".jpg"
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+// Package proximity finds pairs of airborne aircraft closer than a
+// separation minimum in each feed snapshot and follows every encounter to
+// its closest approach.
+package proximity
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"radar/geo"
+	"radar/model"
+	"radar/spatial"
+	"radar/units"
+)
+
+const (
+	// Begin is emitted when a pair first comes within the thresholds.
+	Begin = "begin"
+	// End is emitted once a pair is separated again, or one of them is no
+	// longer in the feed, with the closest approach seen.
+	End = "end"
+)
+
+// Thresholds is the separation minimum: pairs closer than both are
+// reported, pairs exactly at either are separated. Radar separation is
+// typically 5 nm and 1000 ft.
+type Thresholds struct {
+	HorizontalM float64
+	VerticalFt  int
+}
+
+type Aircraft struct {
+	FlightID     string `json:"flight_id"`
+	Callsign     string `json:"callsign,omitempty"`
+	Registration string `json:"registration,omitempty"`
+	Hex          string `json:"hex,omitempty"`
+}
+
+// Approach is the separation between the pair at one snapshot.
+type Approach struct {
+	Time        time.Time      `json:"time"`
+	HorizontalM float64        `json:"horizontal_m"`
+	VerticalFt  int            `json:"vertical_ft"`
+	A           model.Position `json:"a"`
+	B           model.Position `json:"b"`
+}
+
+type Event struct {
+	Type  string    `json:"type"`
+	A     Aircraft  `json:"a"`
+	B     Aircraft  `json:"b"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end,omitempty"`
+	// Closest is the closest approach observed so far. Between snapshots
+	// the aircraft may have come closer still.
+	Closest Approach `json:"closest"`
+}
+
+func (e Event) String() string {
+	return fmt.Sprintf("%s %s/%s and %s/%s %.0fm %dft apart at %s", e.Type,
+		e.A.Callsign, e.A.FlightID, e.B.Callsign, e.B.FlightID,
+		e.Closest.HorizontalM, e.Closest.VerticalFt, e.Closest.Time.Format(time.RFC3339))
+}
+
+// Detector keeps the encounters open between snapshots.
+type Detector struct {
+	limits Thresholds
+
+	mu         sync.Mutex
+	encounters map[string]*Event
+}
+
+func NewDetector(limits Thresholds) *Detector {
+	return &Detector{limits: limits, encounters: make(map[string]*Event)}
+}
+
+// Snapshot checks the positions of one sweep and returns the encounters
+// that began or ended with it. Aircraft on the ground are ignored. idx is
+// the sweep's index of flights, such as spatial.Live's; when nil one is
+// built.
+func (d *Detector) Snapshot(flights []model.Flight, idx *spatial.Index) []Event {
+	if d.limits.HorizontalM <= 0 {
+		return nil
+	}
+	var airborne []model.Flight
+	for _, f := range flights {
+		if f.Position != nil && !f.Position.OnGround {
+			airborne = append(airborne, f)
+		}
+	}
+	if idx == nil {
+		// Cells about as large as the search radius keep each query to a
+		// few cells.
+		idx = spatial.Build(airborne, d.limits.HorizontalM/111000)
+	}
+
+	current := make(map[string]Approach)
+	pairs := make(map[string][2]*model.Flight)
+	for i := range airborne {
+		a := &airborne[i]
+		for _, b := range idx.Radius(point(a), d.limits.HorizontalM) {
+			if b.ID <= a.ID || b.Position.OnGround {
+				continue
+			}
+			vertical := abs(a.Position.Altitude - b.Position.Altitude)
+			if vertical >= d.limits.VerticalFt {
+				continue
+			}
+			horizontal := geo.Distance(point(a), point(b))
+			if horizontal >= d.limits.HorizontalM {
+				continue
+			}
+			key := a.ID + "/" + b.ID
+			at := a.Position.Time
+			if b.Position.Time.After(at) {
+				at = b.Position.Time
+			}
+			current[key] = Approach{
+				Time:        at,
+				HorizontalM: units.Round(horizontal, 0),
+				VerticalFt:  vertical,
+				A:           *a.Position,
+				B:           *b.Position,
+			}
+			pairs[key] = [2]*model.Flight{a, b}
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var events []Event
+	for key, approach := range current {
+		e, ok := d.encounters[key]
+		if !ok {
+			e = &Event{
+				A:       identity(pairs[key][0]),
+				B:       identity(pairs[key][1]),
+				Start:   approach.Time,
+				Closest: approach,
+			}
+			d.encounters[key] = e
+			begin := *e
+			begin.Type = Begin
+			events = append(events, begin)
+		}
+		if approach.HorizontalM < e.Closest.HorizontalM {
+			e.Closest = approach
+		}
+		e.End = approach.Time
+	}
+	for key, e := range d.encounters {
+		if _, ok := current[key]; ok {
+			continue
+		}
+		end := *e
+		end.Type = End
+		events = append(events, end)
+		delete(d.encounters, key)
+	}
+	return events
+}
+
+func identity(f *model.Flight) Aircraft {
+	return Aircraft{FlightID: f.ID, Callsign: f.Callsign, Registration: f.Aircraft.Registration, Hex: f.Aircraft.Hex}
+}
+
+func point(f *model.Flight) geo.Point {
+	return geo.Point{Lat: f.Position.Lat, Lon: f.Position.Lon}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,43 @@
+package proximity
+
+import (
+	"testing"
+	"time"
+
+	"radar/geo"
+	"radar/model"
+	"radar/spatial"
+)
+
+func TestSnapshotThresholds(t *testing.T) {
+	a := geo.Point{Lat: 51.47, Lon: -0.45}
+	b := geo.Point{Lat: 51.47, Lon: -0.35}
+	apart := geo.Distance(a, b)
+
+	tests := []struct {
+		name        string
+		horizontalM float64
+		verticalFt  int
+		want        bool
+	}{
+		{"inside both", apart + 1, 500, true},
+		{"just under vertical", apart + 1, 999, true},
+		{"at vertical", apart + 1, 1000, false},
+		{"above vertical", apart + 1, 1100, false},
+		{"just under horizontal", apart + 0.01, 500, true},
+		{"at horizontal", apart, 500, false},
+		{"beyond horizontal", apart - 1, 500, false},
+	}
+	for _, tt := range tests {
+		d := NewDetector(Thresholds{HorizontalM: tt.horizontalM, VerticalFt: 1000})
+		now := time.Now()
+		flights := []model.Flight{
+			{ID: "a", Position: &model.Position{Time: now, Lat: a.Lat, Lon: a.Lon, Altitude: 30000}},
+			{ID: "b", Position: &model.Position{Time: now, Lat: b.Lat, Lon: b.Lon, Altitude: 30000 + tt.verticalFt}},
+		}
+		events := d.Snapshot(flights, spatial.Build(flights, 0))
+		if got := len(events) == 1 && events[0].Type == Begin; got != tt.want {
+			t.Errorf("%s: reported %v, want %v (%d events)", tt.name, got, tt.want, len(events))
+		}
+	}
+}
@@ -1,9 +1,180 @@
 package main
 
 import (
+	"context"
+	"expvar"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"radar/classify"
+	"radar/detect"
+	"radar/eta"
 	"radar/flightRadar"
+	"radar/geofence"
+	"radar/movement"
+	"radar/photos"
+	"radar/proximity"
+	"radar/reference"
+	"radar/spatial"
+	"radar/track"
 )
 
 func main() {
-	flightRadar.Start()
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		if err := runExport(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	zones := flag.String("zones", "", "comma separated FR24 zone names to sweep instead of flightBounds.json")
+	zonesFile := flag.String("zones-file", "", "saved copy of FR24's zone list, fetched and written when missing")
+	referenceFile := flag.String("reference", "Data/reference.json", "airport and airline reference cache")
+	refresh := flag.Bool("refresh-reference", false, "refresh the reference cache from FR24's static lists before sweeping")
+	pathTemplate := flag.String("path-template", flightRadar.DefaultPathTemplate, "layout of stored records under Data; placeholders {registration} {hex} {flight_id} {callsign} {date} {departure}")
+	photoLimit := flag.Int("photos", 0, "download up to this many photos per registration (0 disables)")
+	photoDir := flag.String("photos-dir", "Data/photos", "content addressed photo store")
+	classes := flag.String("class", "", "comma separated classes of records to store: military, government, state, medical, civil (default all)")
+	squawks := flag.String("squawks", "", "extra alert squawks as code=meaning, comma separated (7500, 7600 and 7700 always alert)")
+	alertsFile := flag.String("alerts", "Data/alerts.jsonl", "file squawk alerts are appended to")
+	simplify := flag.Float64("simplify", 0, "simplify stored trails to within this many meters across track (0 keeps every point)")
+	simplifyAlt := flag.Float64("simplify-alt", 30, "altitude tolerance in meters used with -simplify")
+	resample := flag.Duration("resample", 0, "resample stored trails to one point per interval, e.g. 30s (0 disables)")
+	eventsFile := flag.String("events", "Data/events.jsonl", "file go-around and holding events are appended to")
+	geofences := flag.String("geofences", "", "geofence file, plain JSON or a GeoJSON FeatureCollection")
+	geofenceEvents := flag.String("geofence-events", "Data/geofence_events.jsonl", "file geofence events are appended to")
+	separation := flag.Float64("proximity", 0, "report aircraft pairs closer than this many meters, e.g. 9260 for 5 nm (0 disables)")
+	separationFt := flag.Int("proximity-ft", 1000, "vertical separation in feet used with -proximity")
+	proximityEvents := flag.String("proximity-events", "Data/proximity_events.jsonl", "file proximity events are appended to")
+	movementsFile := flag.String("movements", "Data/movements.jsonl", "file inferred departures and arrivals are appended to")
+	etaFile := flag.String("eta", "Data/eta.jsonl", "file estimated versus actual arrival times are appended to")
+	metrics := flag.String("metrics", "", "address to serve expvar metrics such as fr24_schema_drift on, e.g. localhost:6060 (empty disables)")
+	flag.Parse()
+
+	storedClasses, err := classify.ParseClasses(*classes)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	alertSquawks, err := flightRadar.ParseSquawks(*squawks)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if *metrics != "" {
+		go serveMetrics(*metrics)
+	}
+
+	ctx := context.Background()
+	client, err := flightRadar.NewClient()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	directory, err := reference.Load(*referenceFile)
+	if err != nil {
+		fmt.Println("There was an error reading the reference cache", err)
+		return
+	}
+	if *refresh {
+		if err := directory.Refresh(ctx, client); err != nil {
+			fmt.Println("There was an error refreshing the reference cache", err)
+			return
+		}
+		airports, airlines := directory.Len()
+		fmt.Println("[INF] Reference cache refreshed:", airports, "airports,", airlines, "airlines")
+	}
+
+	var fences []*geofence.Fence
+	if *geofences != "" {
+		if fences, err = geofence.Load(*geofences); err != nil {
+			fmt.Println("There was an error reading the geofences", err)
+			return
+		}
+		fmt.Println("[INF] Loaded", len(fences), "geofences")
+	}
+
+	var photoStore *photos.Store
+	if *photoLimit > 0 {
+		photoStore = photos.NewStore(*photoDir, *photoLimit, client)
+		photoStore.Start(ctx, 2)
+	}
+
+	opts := flightRadar.Options{
+		ZonesFile:    *zonesFile,
+		PathTemplate: *pathTemplate,
+		Classes:      storedClasses,
+		Squawks:      alertSquawks,
+		Resample:     *resample,
+		OnAlert: func(alert flightRadar.Alert) {
+			if err := flightRadar.AppendJSON(*alertsFile, alert); err != nil {
+				fmt.Println("[Err] Could not write the alert", err)
+			}
+		},
+		OnEvent: func(event detect.Event) {
+			if err := flightRadar.AppendJSON(*eventsFile, event); err != nil {
+				fmt.Println("[Err] Could not write the event", err)
+			}
+		},
+		Geofences: fences,
+		OnGeofence: func(event geofence.Event) {
+			if err := flightRadar.AppendJSON(*geofenceEvents, event); err != nil {
+				fmt.Println("[Err] Could not write the geofence event", err)
+			}
+		},
+		Separation: proximity.Thresholds{HorizontalM: *separation, VerticalFt: *separationFt},
+		OnProximity: func(event proximity.Event) {
+			if err := flightRadar.AppendJSON(*proximityEvents, event); err != nil {
+				fmt.Println("[Err] Could not write the proximity event", err)
+			}
+		},
+		Live:      spatial.NewLive(),
+		Airports:  directory,
+		Reference: directory,
+		OnMovement: func(event movement.Event) {
+			if err := flightRadar.AppendJSON(*movementsFile, event); err != nil {
+				fmt.Println("[Err] Could not write the movement", err)
+			}
+		},
+		OnEta: func(outcome eta.Outcome) {
+			if err := flightRadar.AppendJSON(*etaFile, outcome); err != nil {
+				fmt.Println("[Err] Could not write the arrival estimates", err)
+			}
+		},
+		OnDetail: func(details *flightRadar.FlightDetails) {
+			directory.Observe(details)
+			if photoStore != nil {
+				photoStore.Enqueue(details.Canonical().Aircraft)
+			}
+		},
+		OnSweep: func() {
+			if err := directory.SaveIfChanged(); err != nil {
+				fmt.Println("[Err] Could not save the reference cache", err)
+			}
+		},
+	}
+	if *simplify > 0 {
+		opts.Simplify = track.Tolerance{Horizontal: *simplify, Vertical: *simplifyAlt}
+	}
+	if *zones != "" {
+		opts.Zones = strings.Split(*zones, ",")
+	}
+	flightRadar.Start(opts)
+}
+
+// serveMetrics serves /debug/vars on addr for as long as the sweep runs.
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	fmt.Println("[INF] Serving metrics on", addr+"/debug/vars")
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		fmt.Println("[Err] Could not serve metrics", err)
+	}
 }
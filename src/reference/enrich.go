@@ -0,0 +1,153 @@
+package reference
+
+import (
+	"radar/flightRadar"
+	"radar/model"
+)
+
+// Enrichment is the reference data resolved for one flight.
+type Enrichment = flightRadar.Enrichment
+
+// EnrichFlight fills in a canonical flight's airports and airline from the
+// directory. Feed entries only carry the codes; whatever the flight already
+// says is kept and only the blanks are filled.
+func (d *Directory) EnrichFlight(f *model.Flight) {
+	for _, a := range []*Airport{f.Origin, f.Destination, f.Diverted} {
+		if a != nil {
+			fillAirport(a, d.resolve(a))
+		}
+	}
+	if f.Airline == nil {
+		return
+	}
+	if known, ok := d.Airline(f.Airline.Icao); ok {
+		if f.Airline.Name == "" {
+			f.Airline.Name = known.Name
+		}
+		if f.Airline.Iata == "" {
+			f.Airline.Iata = known.Iata
+		}
+	}
+}
+
+// fillAirport copies what a lacks from known.
+func fillAirport(a, known *Airport) {
+	if a == known {
+		return
+	}
+	fill := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	fill(&a.Iata, known.Iata)
+	fill(&a.Icao, known.Icao)
+	fill(&a.Name, known.Name)
+	fill(&a.City, known.City)
+	fill(&a.Country, known.Country)
+	fill(&a.CountryCode, known.CountryCode)
+	if a.Lat == 0 && a.Lon == 0 {
+		a.Lat, a.Lon = known.Lat, known.Lon
+	}
+	if a.Elevation == 0 {
+		a.Elevation = known.Elevation
+	}
+	if a.Timezone == "" {
+		a.Timezone, a.UTCOffset = known.Timezone, known.UTCOffset
+	}
+}
+
+// EnrichDetails resolves the airports and airline of a clickhandler record,
+// preferring the directory entry and falling back to what the record itself
+// carries. A blank airline name or IATA code in the record is filled in.
+func (d *Directory) EnrichDetails(details *flightRadar.FlightDetails) Enrichment {
+	var e Enrichment
+	origin, destination := detailAirports(details)
+	if origin != nil {
+		e.OriginAirport = d.resolve(origin)
+	}
+	if destination != nil {
+		e.DestinationAirport = d.resolve(destination)
+	}
+
+	if a, ok := d.Airline(details.Airline.Code.Icao); ok {
+		e.Airline = a
+		if details.Airline.Name == "" {
+			details.Airline.Name = a.Name
+		}
+		if details.Airline.Code.Iata == "" {
+			details.Airline.Code.Iata = a.Iata
+		}
+	}
+	return e
+}
+
+func (d *Directory) resolve(a *Airport) *Airport {
+	if known, ok := d.Airport(a.Icao); ok {
+		return known
+	}
+	if known, ok := d.Airport(a.Iata); ok {
+		return known
+	}
+	return a
+}
+
+// Observe learns the airports of a clickhandler record, which carry the
+// timezone and city the static list lacks. Call SaveIfChanged to persist.
+func (d *Directory) Observe(details *flightRadar.FlightDetails) {
+	airports := []*Airport{
+		details.Airport.Origin.Canonical(),
+		details.Airport.Destination.Canonical(),
+		details.Airport.Real.Canonical(),
+	}
+	airports = append(airports, historyAirports(details)...)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, a := range airports {
+		if a == nil {
+			continue
+		}
+		known, ok := d.byIcao[a.Icao]
+		if !ok {
+			known, ok = d.byIata[a.Iata]
+		}
+		if !ok {
+			d.addAirport(a)
+			d.dirty = true
+			continue
+		}
+		if a.Timezone != "" && (known.Timezone != a.Timezone || known.UTCOffset != a.UTCOffset) {
+			known.Timezone = a.Timezone
+			known.UTCOffset = a.UTCOffset
+			d.dirty = true
+		}
+		if known.City == "" && a.City != "" {
+			known.City = a.City
+			d.dirty = true
+		}
+		if known.CountryCode == "" && a.CountryCode != "" {
+			known.CountryCode = a.CountryCode
+			d.dirty = true
+		}
+	}
+}
+
+// detailAirports returns where the flight is going, preferring the real
+// destination when FR24 reports a diversion.
+func detailAirports(details *flightRadar.FlightDetails) (origin, destination *Airport) {
+	origin = details.Airport.Origin.Canonical()
+	destination = details.Airport.Real.Canonical()
+	if destination == nil {
+		destination = details.Airport.Destination.Canonical()
+	}
+	return origin, destination
+}
+
+func historyAirports(details *flightRadar.FlightDetails) []*Airport {
+	var airports []*Airport
+	for _, h := range details.FlightHistory.Aircraft {
+		airports = append(airports, h.Airport.Origin.Canonical(), h.Airport.Destination.Canonical())
+	}
+	return airports
+}
@@ -0,0 +1,271 @@
+// Package reference keeps a local directory of airports and airlines used to
+// fill in what FR24's feed and clickhandler records leave out.
+package reference
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"radar/flightRadar"
+	"radar/geo"
+	"radar/model"
+)
+
+// Airport and Airline are the canonical types. Timezone and UTCOffset are
+// not in FR24's static list, they are learned from clickhandler records.
+type (
+	Airport = model.Airport
+	Airline = model.Operator
+)
+
+// cacheFile is the on-disk layout of a Directory.
+type cacheFile struct {
+	Updated  time.Time  `json:"updated"`
+	Airports []*Airport `json:"airports"`
+	Airlines []*Airline `json:"airlines"`
+}
+
+// Directory is safe for concurrent use.
+type Directory struct {
+	file string
+
+	mu       sync.RWMutex
+	updated  time.Time
+	byIata   map[string]*Airport
+	byIcao   map[string]*Airport
+	airlines map[string]*Airline
+	dirty    bool
+}
+
+func newDirectory(file string) *Directory {
+	return &Directory{
+		file:     file,
+		byIata:   make(map[string]*Airport),
+		byIcao:   make(map[string]*Airport),
+		airlines: make(map[string]*Airline),
+	}
+}
+
+// Load reads the cache file. A missing file gives an empty directory that
+// can be filled with Refresh.
+func Load(file string) (*Directory, error) {
+	d := newDirectory(file)
+	body, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cache cacheFile
+	if err := json.Unmarshal(body, &cache); err != nil {
+		return nil, err
+	}
+	d.updated = cache.Updated
+	for _, a := range cache.Airports {
+		d.addAirport(a)
+	}
+	for _, a := range cache.Airlines {
+		d.airlines[a.Icao] = a
+	}
+	return d, nil
+}
+
+// Save writes the directory back to its cache file.
+func (d *Directory) Save() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	cache := cacheFile{Updated: d.updated, Airports: d.allAirports()}
+	for _, a := range d.airlines {
+		cache.Airlines = append(cache.Airlines, a)
+	}
+	sort.Slice(cache.Airports, func(i, j int) bool {
+		return cache.Airports[i].Icao+cache.Airports[i].Iata < cache.Airports[j].Icao+cache.Airports[j].Iata
+	})
+	sort.Slice(cache.Airlines, func(i, j int) bool { return cache.Airlines[i].Icao < cache.Airlines[j].Icao })
+
+	body, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(d.file), 0777); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(d.file, body, 0644); err != nil {
+		return err
+	}
+	d.dirty = false
+	return nil
+}
+
+// SaveIfChanged saves the directory only when Observe learned something new.
+func (d *Directory) SaveIfChanged() error {
+	d.mu.RLock()
+	dirty := d.dirty
+	d.mu.RUnlock()
+	if !dirty {
+		return nil
+	}
+	return d.Save()
+}
+
+// Updated is when the directory was last refreshed from FR24.
+func (d *Directory) Updated() time.Time {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.updated
+}
+
+// Refresh replaces the directory with FR24's static airport and airline
+// lists and saves it. Timezones and cities learned so far are kept.
+func (d *Directory) Refresh(ctx context.Context, client *flightRadar.Client) error {
+	airports, err := client.AirportList(ctx)
+	if err != nil {
+		return err
+	}
+	airlines, err := client.AirlineList(ctx)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	old := d.byIcao
+	oldIata := d.byIata
+	d.byIata = make(map[string]*Airport)
+	d.byIcao = make(map[string]*Airport)
+	for _, s := range airports {
+		a := &Airport{
+			Iata:      s.Iata,
+			Icao:      s.Icao,
+			Name:      s.Name,
+			Lat:       s.Lat,
+			Lon:       s.Lon,
+			Elevation: s.Alt,
+			Country:   s.Country,
+		}
+		prev, ok := old[s.Icao]
+		if !ok {
+			prev, ok = oldIata[s.Iata]
+		}
+		if ok {
+			a.City = prev.City
+			a.CountryCode = prev.CountryCode
+			a.Timezone = prev.Timezone
+			a.UTCOffset = prev.UTCOffset
+		}
+		d.addAirport(a)
+	}
+	d.airlines = make(map[string]*Airline)
+	for _, s := range airlines {
+		if s.Icao == "" {
+			continue
+		}
+		d.airlines[s.Icao] = &Airline{Icao: s.Icao, Iata: s.Code, Name: s.Name}
+	}
+	d.updated = time.Now().UTC()
+	d.mu.Unlock()
+
+	return d.Save()
+}
+
+// addAirport indexes a by both codes. The caller holds the lock.
+func (d *Directory) addAirport(a *Airport) {
+	if a.Iata != "" {
+		d.byIata[a.Iata] = a
+	}
+	if a.Icao != "" {
+		d.byIcao[a.Icao] = a
+	}
+}
+
+// allAirports lists every airport once. The caller holds the lock.
+func (d *Directory) allAirports() []*Airport {
+	var airports []*Airport
+	seen := make(map[*Airport]bool)
+	for _, m := range []map[string]*Airport{d.byIcao, d.byIata} {
+		for _, a := range m {
+			if !seen[a] {
+				seen[a] = true
+				airports = append(airports, a)
+			}
+		}
+	}
+	return airports
+}
+
+// Airport looks an airport up by IATA or ICAO code.
+func (d *Directory) Airport(code string) (*Airport, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return nil, false
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	a, ok := d.byIata[code]
+	if !ok {
+		a, ok = d.byIcao[code]
+	}
+	if !ok {
+		return nil, false
+	}
+	cp := *a
+	return &cp, true
+}
+
+// Airline looks an airline up by ICAO code.
+func (d *Directory) Airline(icao string) (*Airline, bool) {
+	icao = strings.ToUpper(strings.TrimSpace(icao))
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	a, ok := d.airlines[icao]
+	if !ok {
+		return nil, false
+	}
+	cp := *a
+	return &cp, true
+}
+
+// Nearest returns the airport closest to p within radius meters. Airports
+// without a position are skipped.
+func (d *Directory) Nearest(p geo.Point, radius float64) (*Airport, float64, bool) {
+	// Degrees of latitude the radius spans, to skip most airports cheaply.
+	span := radius / 111000
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var best *Airport
+	for _, m := range []map[string]*Airport{d.byIcao, d.byIata} {
+		for _, a := range m {
+			if (a.Lat == 0 && a.Lon == 0) || math.Abs(a.Lat-p.Lat) > span {
+				continue
+			}
+			if dist := geo.Distance(p, geo.Point{Lat: a.Lat, Lon: a.Lon}); dist <= radius {
+				best, radius = a, dist
+			}
+		}
+	}
+	if best == nil {
+		return nil, 0, false
+	}
+	cp := *best
+	return &cp, radius, true
+}
+
+// Len returns how many airports and airlines the directory holds.
+func (d *Directory) Len() (airports, airlines int) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.allAirports()), len(d.airlines)
+}
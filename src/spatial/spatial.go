@@ -0,0 +1,199 @@
+// Package spatial indexes flight positions in a lat/lon grid so nearby
+// flights can be found without comparing every pair.
+package spatial
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"radar/geo"
+	"radar/model"
+)
+
+// DefaultCell is the grid cell size in degrees.
+const DefaultCell = 0.5
+
+type cell struct{ lat, lon int }
+
+// Index holds flights by their current position. It is built once per
+// snapshot and not safe for concurrent writes; once built any number of
+// goroutines may query it. Returned flights point into the index and must
+// not be modified.
+type Index struct {
+	size  float64
+	cells map[cell][]*model.Flight
+	n     int
+}
+
+// New returns an empty index with cells of size degrees (DefaultCell when
+// zero).
+func New(size float64) *Index {
+	if size <= 0 {
+		size = DefaultCell
+	}
+	return &Index{size: size, cells: make(map[cell][]*model.Flight)}
+}
+
+// Build indexes every flight that has a position.
+func Build(flights []model.Flight, size float64) *Index {
+	idx := New(size)
+	for i := range flights {
+		idx.Insert(&flights[i])
+	}
+	return idx
+}
+
+// Insert adds f at f.Position; flights without one are skipped.
+func (idx *Index) Insert(f *model.Flight) {
+	if f.Position == nil {
+		return
+	}
+	c := idx.cellOf(f.Position.Lat, f.Position.Lon)
+	idx.cells[c] = append(idx.cells[c], f)
+	idx.n++
+}
+
+func (idx *Index) Len() int {
+	return idx.n
+}
+
+func (idx *Index) cellOf(lat, lon float64) cell {
+	return cell{int(math.Floor(lat / idx.size)), idx.wrap(int(math.Floor(lon / idx.size)))}
+}
+
+// wrap keeps longitude cells in range across the antimeridian.
+func (idx *Index) wrap(lon int) int {
+	n := int(math.Ceil(360 / idx.size))
+	half := n / 2
+	return ((lon+half)%n+n)%n - half
+}
+
+// Radius returns the flights within radius meters of p.
+func (idx *Index) Radius(p geo.Point, radius float64) []*model.Flight {
+	dLat, dLon := geo.Extent(p, radius)
+	var found []*model.Flight
+	idx.scan(p.Lat-dLat, p.Lon-dLon, p.Lat+dLat, p.Lon+dLon, func(f *model.Flight) {
+		if geo.Distance(p, point(f)) <= radius {
+			found = append(found, f)
+		}
+	})
+	return found
+}
+
+// BBox returns the flights inside the box. A box with minLon > maxLon
+// crosses the antimeridian.
+func (idx *Index) BBox(minLat, minLon, maxLat, maxLon float64) []*model.Flight {
+	spans := [][2]float64{{minLon, maxLon}}
+	if minLon > maxLon {
+		spans = [][2]float64{{minLon, 180}, {-180, maxLon}}
+	}
+	var found []*model.Flight
+	for _, span := range spans {
+		idx.scan(minLat, span[0], maxLat, span[1], func(f *model.Flight) {
+			p := f.Position
+			if p.Lat >= minLat && p.Lat <= maxLat && p.Lon >= span[0] && p.Lon <= span[1] {
+				found = append(found, f)
+			}
+		})
+	}
+	return found
+}
+
+// Nearest returns the k flights closest to p, closest first.
+func (idx *Index) Nearest(p geo.Point, k int) []*model.Flight {
+	if k <= 0 || idx.n == 0 {
+		return nil
+	}
+	var found []*model.Flight
+	if idx.n <= k {
+		idx.scan(-90, -180, 90, 180, func(f *model.Flight) { found = append(found, f) })
+	} else {
+		// Grow a square of cells around p, doubling its side, until it
+		// holds k flights. The k-th of those bounds the distance of the
+		// true k nearest, which a radius query then collects exactly.
+		center := idx.cellOf(p.Lat, p.Lon)
+		var candidates []*model.Flight
+		for r := 0; len(candidates) < k; r = 2*r + 1 {
+			candidates = candidates[:0]
+			lo := geo.Point{Lat: float64(center.lat-r) * idx.size, Lon: float64(center.lon-r) * idx.size}
+			hi := geo.Point{Lat: float64(center.lat+r+1) * idx.size, Lon: float64(center.lon+r+1) * idx.size}
+			idx.scan(lo.Lat, lo.Lon, hi.Lat-idx.size/2, hi.Lon-idx.size/2, func(f *model.Flight) {
+				candidates = append(candidates, f)
+			})
+		}
+		sortByDistance(p, candidates)
+		found = idx.Radius(p, geo.Distance(p, point(candidates[k-1])))
+	}
+	sortByDistance(p, found)
+	if len(found) > k {
+		found = found[:k]
+	}
+	return found
+}
+
+func sortByDistance(p geo.Point, flights []*model.Flight) {
+	sort.Slice(flights, func(i, j int) bool {
+		return geo.Distance(p, point(flights[i])) < geo.Distance(p, point(flights[j]))
+	})
+}
+
+// scan calls fn for every flight in the cells covering the box.
+func (idx *Index) scan(minLat, minLon, maxLat, maxLon float64, fn func(f *model.Flight)) {
+	lo := cell{int(math.Floor(minLat / idx.size)), int(math.Floor(minLon / idx.size))}
+	hi := cell{int(math.Floor(maxLat / idx.size)), int(math.Floor(maxLon / idx.size))}
+	seen := make(map[int]bool)
+	for lon := lo.lon; lon <= hi.lon; lon++ {
+		w := idx.wrap(lon)
+		if seen[w] {
+			continue
+		}
+		seen[w] = true
+		for lat := lo.lat; lat <= hi.lat; lat++ {
+			for _, f := range idx.cells[cell{lat, w}] {
+				fn(f)
+			}
+		}
+	}
+}
+
+func point(f *model.Flight) geo.Point {
+	return geo.Point{Lat: f.Position.Lat, Lon: f.Position.Lon}
+}
+
+// Live holds the index of the latest sweep for readers elsewhere in the
+// process. The sweep replaces it wholesale, so readers always see one
+// complete snapshot.
+type Live struct {
+	mu      sync.RWMutex
+	idx     *Index
+	updated time.Time
+}
+
+func NewLive() *Live {
+	return &Live{idx: New(0)}
+}
+
+// Replace indexes the positions of a finished sweep.
+func (l *Live) Replace(flights []model.Flight) {
+	idx := Build(flights, 0)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.idx, l.updated = idx, time.Now()
+}
+
+// Index is the current snapshot. Hold on to it for a consistent view across
+// several queries.
+func (l *Live) Index() *Index {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.idx
+}
+
+// Updated is when the index was last replaced.
+func (l *Live) Updated() time.Time {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.updated
+}
@@ -0,0 +1,129 @@
+package spatial
+
+import (
+	"slices"
+	"sort"
+	"testing"
+
+	"radar/geo"
+	"radar/model"
+)
+
+// fleet has flights spread over a few cells, two of them on either side of
+// the antimeridian, and one without a position.
+var fleet = []model.Flight{
+	{ID: "fra", Position: &model.Position{Lat: 50.03, Lon: 8.57}},
+	{ID: "fra-north", Position: &model.Position{Lat: 50.13, Lon: 8.57}},
+	{ID: "fra-east", Position: &model.Position{Lat: 50.03, Lon: 9.07}},
+	{ID: "muc", Position: &model.Position{Lat: 48.35, Lon: 11.79}},
+	{ID: "lhr", Position: &model.Position{Lat: 51.47, Lon: -0.45}},
+	{ID: "fiji-east", Position: &model.Position{Lat: -17.0, Lon: 179.9}},
+	{ID: "fiji-west", Position: &model.Position{Lat: -17.0, Lon: -179.9}},
+	{ID: "grounded"},
+}
+
+func ids(flights []*model.Flight) []string {
+	var out []string
+	for _, f := range flights {
+		out = append(out, f.ID)
+	}
+	return out
+}
+
+func sorted(flights []*model.Flight) []string {
+	out := ids(flights)
+	sort.Strings(out)
+	return out
+}
+
+func TestRadius(t *testing.T) {
+	frankfurt := geo.Point{Lat: 50.03, Lon: 8.57}
+	tests := []struct {
+		name   string
+		p      geo.Point
+		radius float64
+		want   []string
+	}{
+		{"nothing", geo.Point{Lat: 0, Lon: 0}, 100000, nil},
+		{"itself", frankfurt, 1000, []string{"fra"}},
+		// fra-north is 11.1 km away, fra-east 35.8 km.
+		{"north only", frankfurt, 12000, []string{"fra", "fra-north"}},
+		{"across cells", frankfurt, 40000, []string{"fra", "fra-east", "fra-north"}},
+		{"across the antimeridian", geo.Point{Lat: -17.0, Lon: 179.95}, 20000, []string{"fiji-east", "fiji-west"}},
+	}
+	for _, size := range []float64{0.1, DefaultCell, 5} {
+		idx := Build(fleet, size)
+		if idx.Len() != len(fleet)-1 {
+			t.Errorf("cell %v: indexed %d flights, want %d", size, idx.Len(), len(fleet)-1)
+		}
+		for _, tt := range tests {
+			if got := sorted(idx.Radius(tt.p, tt.radius)); !slices.Equal(got, tt.want) {
+				t.Errorf("cell %v, %s: %v, want %v", size, tt.name, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestBBox(t *testing.T) {
+	tests := []struct {
+		name                           string
+		minLat, minLon, maxLat, maxLon float64
+		want                           []string
+	}{
+		{"empty", 0, 0, 1, 1, nil},
+		{"germany", 47, 5, 55, 15, []string{"fra", "fra-east", "fra-north", "muc"}},
+		{"edges are inside", 50.03, 8.57, 50.13, 9.07, []string{"fra", "fra-east", "fra-north"}},
+		{"across the antimeridian", -20, 179, -10, -179, []string{"fiji-east", "fiji-west"}},
+		{"west of the antimeridian only", -20, -180, -10, -179, []string{"fiji-west"}},
+	}
+	for _, size := range []float64{0.1, DefaultCell, 5} {
+		idx := Build(fleet, size)
+		for _, tt := range tests {
+			if got := sorted(idx.BBox(tt.minLat, tt.minLon, tt.maxLat, tt.maxLon)); !slices.Equal(got, tt.want) {
+				t.Errorf("cell %v, %s: %v, want %v", size, tt.name, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestNearest(t *testing.T) {
+	frankfurt := geo.Point{Lat: 50.03, Lon: 8.57}
+	tests := []struct {
+		name string
+		p    geo.Point
+		k    int
+		want []string // closest first
+	}{
+		{"none", frankfurt, 0, nil},
+		{"closest", frankfurt, 1, []string{"fra"}},
+		{"three closest", frankfurt, 3, []string{"fra", "fra-north", "fra-east"}},
+		{"beyond the first cells", frankfurt, 5, []string{"fra", "fra-north", "fra-east", "muc", "lhr"}},
+		{"more than indexed", frankfurt, 20, []string{"fra", "fra-north", "fra-east", "muc", "lhr", "fiji-east", "fiji-west"}},
+		{"far from everything", geo.Point{Lat: 0, Lon: -100}, 1, []string{"fiji-west"}},
+		{"across the antimeridian from the west", geo.Point{Lat: -17.0, Lon: -179.99}, 2, []string{"fiji-west", "fiji-east"}},
+		{"across the antimeridian from the east", geo.Point{Lat: -17.0, Lon: 179.99}, 2, []string{"fiji-east", "fiji-west"}},
+	}
+	for _, size := range []float64{0.1, DefaultCell, 5} {
+		idx := Build(fleet, size)
+		for _, tt := range tests {
+			if got := ids(idx.Nearest(tt.p, tt.k)); !slices.Equal(got, tt.want) {
+				t.Errorf("cell %v, %s: %v, want %v", size, tt.name, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestLive(t *testing.T) {
+	live := NewLive()
+	if live.Index().Len() != 0 || !live.Updated().IsZero() {
+		t.Fatal("new live index is not empty")
+	}
+	before := live.Index()
+	live.Replace(fleet)
+	if live.Index().Len() != len(fleet)-1 || live.Updated().IsZero() {
+		t.Errorf("replaced index has %d flights", live.Index().Len())
+	}
+	if before.Len() != 0 {
+		t.Error("replacing changed an index a reader held")
+	}
+}
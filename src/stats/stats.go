@@ -0,0 +1,130 @@
+// Package stats derives per-flight figures from a flight's trail and the
+// positions of its airports.
+package stats
+
+import (
+	"math"
+	"time"
+
+	"radar/geo"
+	"radar/model"
+	"radar/units"
+)
+
+// cruiseBand is how far below the highest altitude a position still counts
+// as cruise, in feet.
+const cruiseBand = 1000
+
+// originRadius is how close to the origin, in meters, a trail must start for
+// its flown distance to cover the whole route.
+const originRadius = 10000
+
+// Stats are in SI units, with altitude and speed also in feet and knots as
+// positions carry them. Fields that cannot be derived are left out.
+type Stats struct {
+	// FlownDistanceM is the length of the trail.
+	FlownDistanceM float64 `json:"flown_distance_m"`
+	// GreatCircleM is the distance from the origin to where the flight
+	// lands (the diversion airport if any).
+	GreatCircleM *float64 `json:"great_circle_m,omitempty"`
+	// RouteEfficiency is GreatCircleM over FlownDistanceM, up to 1. It is
+	// only set once the flight has landed and when its trail starts on the
+	// ground at the origin, see Landed and fromOrigin.
+	RouteEfficiency *float64 `json:"route_efficiency,omitempty"`
+	MaxAltitude     int      `json:"max_altitude"`
+	MaxAltitudeM    float64  `json:"max_altitude_m"`
+	// CruiseSpeed averages the ground speed over time spent within
+	// cruiseBand of the highest altitude.
+	CruiseSpeed    int     `json:"avg_cruise_speed,omitempty"`
+	CruiseSpeedMps float64 `json:"avg_cruise_speed_mps,omitempty"`
+	// AirborneSeconds sums the time between consecutive airborne positions.
+	AirborneSeconds int64 `json:"airborne_seconds"`
+	Points          int   `json:"points"`
+}
+
+// Flight computes the stats of f. Its trail must be oldest first, as
+// canonical trails are.
+func Flight(f *model.Flight) Stats {
+	trail := f.Trail
+	s := Stats{Points: len(trail)}
+	for i, p := range trail {
+		if p.Altitude > s.MaxAltitude {
+			s.MaxAltitude = p.Altitude
+		}
+		if i == 0 {
+			continue
+		}
+		prev := trail[i-1]
+		s.FlownDistanceM += geo.Distance(point(prev), point(p))
+		if !prev.OnGround && !p.OnGround {
+			s.AirborneSeconds += int64(p.Time.Sub(prev.Time) / time.Second)
+		}
+	}
+	s.FlownDistanceM = units.Round(s.FlownDistanceM, 0)
+	s.MaxAltitudeM = units.Round(units.FeetToMeters(float64(s.MaxAltitude)), 1)
+
+	// Time weighted so dense and sparse parts of the trail count alike.
+	var speed, seconds float64
+	for i := 1; i < len(trail); i++ {
+		prev, p := trail[i-1], trail[i]
+		if p.OnGround || p.Altitude < s.MaxAltitude-cruiseBand || prev.Altitude < s.MaxAltitude-cruiseBand {
+			continue
+		}
+		dt := p.Time.Sub(prev.Time).Seconds()
+		speed += float64(prev.GroundSpeed+p.GroundSpeed) / 2 * dt
+		seconds += dt
+	}
+	if seconds > 0 && s.MaxAltitude > 0 {
+		kts := speed / seconds
+		s.CruiseSpeed = int(units.Round(kts, 0))
+		s.CruiseSpeedMps = units.Round(units.KnotsToMps(kts), 2)
+	}
+
+	if origin, arrival := f.Origin, f.ArrivalAirport(); located(origin) && located(arrival) {
+		gc := units.Round(geo.Distance(geo.Point{Lat: origin.Lat, Lon: origin.Lon}, geo.Point{Lat: arrival.Lat, Lon: arrival.Lon}), 0)
+		s.GreatCircleM = &gc
+		if s.FlownDistanceM > 0 && Landed(f) && fromOrigin(f) {
+			// Taxiing and position noise can still make the trail a
+			// little shorter than the great circle.
+			e := units.Round(math.Min(1, gc/s.FlownDistanceM), 3)
+			s.RouteEfficiency = &e
+		}
+	}
+	return s
+}
+
+// Landed reports whether f has an actual arrival time or touches down in
+// its trail. Before that the flown distance is still growing towards the
+// great circle one.
+func Landed(f *model.Flight) bool {
+	if f.Times.ActualArrival != nil {
+		return true
+	}
+	for i := 1; i < len(f.Trail); i++ {
+		if !f.Trail[i-1].OnGround && f.Trail[i].OnGround {
+			return true
+		}
+	}
+	return false
+}
+
+// fromOrigin reports whether f's trail starts on the ground at its origin.
+// FR24 trails often start after takeoff, and their flown distance then
+// falls short of the route.
+func fromOrigin(f *model.Flight) bool {
+	if len(f.Trail) == 0 || !f.Trail[0].OnGround {
+		return false
+	}
+	origin := geo.Point{Lat: f.Origin.Lat, Lon: f.Origin.Lon}
+	return geo.Distance(point(f.Trail[0]), origin) <= originRadius
+}
+
+// located reports whether the airport's position is known; FR24 sends 0,0
+// for airports it has no data on.
+func located(a *model.Airport) bool {
+	return a != nil && (a.Lat != 0 || a.Lon != 0)
+}
+
+func point(p model.Position) geo.Point {
+	return geo.Point{Lat: p.Lat, Lon: p.Lon}
+}
@@ -0,0 +1,64 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"radar/model"
+)
+
+var (
+	origin      = &model.Airport{Icao: "EDDF", Lat: 50.0, Lon: 8.5}
+	destination = &model.Airport{Icao: "EDDH", Lat: 51.0, Lon: 8.5}
+)
+
+// trail is one position a minute through points of lat, lon and altitude,
+// on the ground at altitude 0.
+func trail(points ...[3]float64) []model.Position {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	positions := make([]model.Position, len(points))
+	for i, p := range points {
+		positions[i] = model.Position{
+			Time:     start.Add(time.Duration(i) * time.Minute),
+			Lat:      p[0],
+			Lon:      p[1],
+			Altitude: int(p[2]),
+			OnGround: p[2] == 0,
+		}
+	}
+	return positions
+}
+
+func TestRouteEfficiency(t *testing.T) {
+	arrived := time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		trail   []model.Position
+		arrival *time.Time
+		want    float64 // 0 when not set
+	}{
+		{"landed", trail([3]float64{50, 8.5, 0}, [3]float64{50.5, 8.5, 30000}, [3]float64{51, 8.5, 0}), nil, 1},
+		{"landed with a detour", trail([3]float64{50, 8.5, 0}, [3]float64{50.5, 9.5, 30000}, [3]float64{51, 8.5, 0}), nil, 0.618},
+		{"landed short of the airport position", trail([3]float64{50, 8.5, 0}, [3]float64{50.5, 8.5, 30000}, [3]float64{50.97, 8.5, 0}), nil, 1},
+		{"not landed", trail([3]float64{50, 8.5, 0}, [3]float64{50.5, 8.5, 30000}, [3]float64{50.9, 8.5, 3000}), nil, 0},
+		{"arrival time before the trail touches down", trail([3]float64{50, 8.5, 0}, [3]float64{50.5, 9.5, 30000}, [3]float64{51, 8.5, 500}), &arrived, 0.618},
+		{"trail starts airborne", trail([3]float64{50.5, 8.5, 30000}, [3]float64{51, 8.5, 0}), nil, 0},
+		{"trail starts away from the origin", trail([3]float64{50.5, 8.5, 0}, [3]float64{50.7, 8.5, 30000}, [3]float64{51, 8.5, 0}), nil, 0},
+	}
+	for _, tt := range tests {
+		f := &model.Flight{Origin: origin, Destination: destination, Trail: tt.trail}
+		f.Times.ActualArrival = tt.arrival
+		s := Flight(f)
+		if s.GreatCircleM == nil {
+			t.Errorf("%s: no great circle distance", tt.name)
+			continue
+		}
+		var got float64
+		if s.RouteEfficiency != nil {
+			got = *s.RouteEfficiency
+		}
+		if got != tt.want {
+			t.Errorf("%s: route efficiency %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
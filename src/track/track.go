@@ -0,0 +1,154 @@
+// Package track reduces position tracks for storage and export: Douglas-
+// Peucker simplification and fixed interval resampling. Tracks are oldest
+// position first.
+package track
+
+import (
+	"math"
+	"time"
+
+	"radar/geo"
+	"radar/model"
+	"radar/units"
+)
+
+// Tolerance is how far, in meters, a dropped position may lie from the
+// simplified track: Horizontal across it and Vertical from the altitude
+// interpolated along it. A zero tolerance ignores that dimension.
+type Tolerance struct {
+	Horizontal float64
+	Vertical   float64
+}
+
+func (t Tolerance) IsZero() bool {
+	return t.Horizontal <= 0 && t.Vertical <= 0
+}
+
+// Simplify keeps the positions Douglas-Peucker needs to stay within tol.
+// The first and last positions are always kept.
+func Simplify(points []model.Position, tol Tolerance) []model.Position {
+	keep := SimplifyIndices(points, tol)
+	out := make([]model.Position, len(keep))
+	for i, k := range keep {
+		out[i] = points[k]
+	}
+	return out
+}
+
+// SimplifyIndices is Simplify returning the indices of the kept positions,
+// for callers that keep their own point type alongside.
+func SimplifyIndices(points []model.Position, tol Tolerance) []int {
+	n := len(points)
+	if n <= 2 || tol.IsZero() {
+		keep := make([]int, n)
+		for i := range keep {
+			keep[i] = i
+		}
+		return keep
+	}
+
+	kept := make([]bool, n)
+	kept[0], kept[n-1] = true, true
+	// An explicit stack, long-haul tracks can be thousands of points.
+	stack := [][2]int{{0, n - 1}}
+	for len(stack) > 0 {
+		first, last := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+		worst, index := 1.0, -1
+		for i := first + 1; i < last; i++ {
+			if e := deviation(points[i], points[first], points[last], tol); e > worst {
+				worst, index = e, i
+			}
+		}
+		if index >= 0 {
+			kept[index] = true
+			stack = append(stack, [2]int{first, index}, [2]int{index, last})
+		}
+	}
+
+	var keep []int
+	for i, k := range kept {
+		if k {
+			keep = append(keep, i)
+		}
+	}
+	return keep
+}
+
+// deviation is how far p lies from the segment a-b as a multiple of tol;
+// above 1 it has to be kept.
+func deviation(p, a, b model.Position, tol Tolerance) float64 {
+	distance, along := geo.SegmentDistance(point(p), point(a), point(b))
+	e := 0.0
+	if tol.Horizontal > 0 {
+		e = distance / tol.Horizontal
+	}
+	if tol.Vertical > 0 {
+		var f float64
+		if span := b.Time.Sub(a.Time); span > 0 {
+			f = float64(p.Time.Sub(a.Time)) / float64(span)
+		} else if length := geo.Distance(point(a), point(b)); length > 0 {
+			f = along / length
+		}
+		expected := altitude(a) + (altitude(b)-altitude(a))*f
+		e = math.Max(e, math.Abs(altitude(p)-expected)/tol.Vertical)
+	}
+	return e
+}
+
+// Resample returns positions every interval from the first position's time,
+// interpolated between the reported ones, followed by the last position.
+func Resample(points []model.Position, interval time.Duration) []model.Position {
+	if len(points) < 2 || interval <= 0 {
+		return points
+	}
+	last := points[len(points)-1]
+	var out []model.Position
+	i := 0
+	for t := points[0].Time; t.Before(last.Time); t = t.Add(interval) {
+		for i < len(points)-2 && !points[i+1].Time.After(t) {
+			i++
+		}
+		out = append(out, Interpolate(points[i], points[i+1], t))
+	}
+	return append(out, last)
+}
+
+// Interpolate estimates the position at t between a and b: along the great
+// circle, with altitude, speeds and track changing linearly.
+func Interpolate(a, b model.Position, t time.Time) model.Position {
+	span := b.Time.Sub(a.Time)
+	if span <= 0 {
+		return a
+	}
+	f := math.Max(0, math.Min(1, float64(t.Sub(a.Time))/float64(span)))
+	at := geo.Interpolate(point(a), point(b), f)
+	p := model.Position{
+		Time:         t,
+		Lat:          at.Lat,
+		Lon:          at.Lon,
+		Altitude:     lerp(a.Altitude, b.Altitude, f),
+		GroundSpeed:  lerp(a.GroundSpeed, b.GroundSpeed, f),
+		VerticalRate: lerp(a.VerticalRate, b.VerticalRate, f),
+		OnGround:     a.OnGround && b.OnGround,
+		Squawk:       a.Squawk,
+		Phase:        a.Phase,
+	}
+	// Turn the short way round.
+	turn := math.Mod(float64(b.Track-a.Track)+540, 360) - 180
+	p.Track = int(math.Round(math.Mod(float64(a.Track)+turn*f+360, 360))) % 360
+	p.Normalize()
+	return p
+}
+
+func lerp(a, b int, f float64) int {
+	return int(math.Round(float64(a) + float64(b-a)*f))
+}
+
+func point(p model.Position) geo.Point {
+	return geo.Point{Lat: p.Lat, Lon: p.Lon}
+}
+
+func altitude(p model.Position) float64 {
+	return units.FeetToMeters(float64(p.Altitude))
+}
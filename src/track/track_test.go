@@ -0,0 +1,101 @@
+package track
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"radar/model"
+)
+
+var start = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// path is one position a minute through points of lat, lon and altitude.
+func path(points ...[3]float64) []model.Position {
+	positions := make([]model.Position, len(points))
+	for i, p := range points {
+		positions[i] = model.Position{
+			Time:     start.Add(time.Duration(i) * time.Minute),
+			Lat:      p[0],
+			Lon:      p[1],
+			Altitude: int(p[2]),
+		}
+		positions[i].Normalize()
+	}
+	return positions
+}
+
+func TestSimplifyIndices(t *testing.T) {
+	straight := path([3]float64{0, 0, 10000}, [3]float64{0, 0.01, 10000}, [3]float64{0, 0.02, 10000}, [3]float64{0, 0.03, 10000})
+	corner := path([3]float64{0, 0, 10000}, [3]float64{0, 0.01, 10000}, [3]float64{0, 0.02, 10000}, [3]float64{0.01, 0.02, 10000}, [3]float64{0.02, 0.02, 10000})
+	wobble := path([3]float64{0, 0, 10000}, [3]float64{0.0005, 0.01, 10000}, [3]float64{0, 0.02, 10000})
+	climb := path([3]float64{0, 0, 10000}, [3]float64{0, 0.01, 12000}, [3]float64{0, 0.02, 14000})
+	bump := path([3]float64{0, 0, 10000}, [3]float64{0, 0.01, 11000}, [3]float64{0, 0.02, 10000})
+
+	tests := []struct {
+		name   string
+		points []model.Position
+		tol    Tolerance
+		want   []int
+	}{
+		{"empty", nil, Tolerance{Horizontal: 100}, []int{}},
+		{"two points", straight[:2], Tolerance{Horizontal: 100}, []int{0, 1}},
+		{"zero tolerance", straight, Tolerance{}, []int{0, 1, 2, 3}},
+		{"straight", straight, Tolerance{Horizontal: 100}, []int{0, 3}},
+		{"corner", corner, Tolerance{Horizontal: 100}, []int{0, 2, 4}},
+		{"wobble within tolerance", wobble, Tolerance{Horizontal: 100}, []int{0, 2}},
+		{"wobble beyond tolerance", wobble, Tolerance{Horizontal: 10}, []int{0, 1, 2}},
+		{"steady climb", climb, Tolerance{Horizontal: 100, Vertical: 30}, []int{0, 2}},
+		{"altitude bump", bump, Tolerance{Horizontal: 100, Vertical: 30}, []int{0, 1, 2}},
+		{"altitude bump without vertical tolerance", bump, Tolerance{Horizontal: 100}, []int{0, 2}},
+	}
+	for _, tt := range tests {
+		if got := SimplifyIndices(tt.points, tt.tol); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: kept %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestResample(t *testing.T) {
+	climb := path([3]float64{0, 0, 1000}, [3]float64{0, 0.01, 2000}, [3]float64{0, 0.02, 4000})
+	tests := []struct {
+		name      string
+		interval  time.Duration
+		seconds   []int // since start
+		altitudes []int
+	}{
+		{"disabled", 0, []int{0, 60, 120}, []int{1000, 2000, 4000}},
+		{"every 30s", 30 * time.Second, []int{0, 30, 60, 90, 120}, []int{1000, 1500, 2000, 3000, 4000}},
+		{"every 50s keeps the last", 50 * time.Second, []int{0, 50, 100, 120}, []int{1000, 1833, 3333, 4000}},
+		{"longer than the track", time.Hour, []int{0, 120}, []int{1000, 4000}},
+	}
+	for _, tt := range tests {
+		var seconds, altitudes []int
+		for _, p := range Resample(climb, tt.interval) {
+			seconds = append(seconds, int(p.Time.Sub(start)/time.Second))
+			altitudes = append(altitudes, p.Altitude)
+		}
+		if !slices.Equal(seconds, tt.seconds) || !slices.Equal(altitudes, tt.altitudes) {
+			t.Errorf("%s: times %v altitudes %v, want %v %v", tt.name, seconds, altitudes, tt.seconds, tt.altitudes)
+		}
+	}
+}
+
+func TestInterpolateTrack(t *testing.T) {
+	tests := []struct {
+		from, to int
+		want     int
+	}{
+		{80, 100, 90},
+		{350, 10, 0},
+		{10, 350, 0},
+		{170, 190, 180},
+	}
+	for _, tt := range tests {
+		a := model.Position{Time: start, Track: tt.from}
+		b := model.Position{Time: start.Add(time.Minute), Track: tt.to}
+		if got := Interpolate(a, b, start.Add(30*time.Second)).Track; got != tt.want {
+			t.Errorf("%d to %d: track %d, want %d", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
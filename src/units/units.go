@@ -0,0 +1,113 @@
+// Package units converts between the aviation units sources report in and
+// SI, and renders values in the unit system chosen for output.
+package units
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	MetersPerFoot         = 0.3048
+	MetersPerNauticalMile = 1852.0
+	// MpsPerKnot is meters per second in one knot.
+	MpsPerKnot = MetersPerNauticalMile / 3600
+	// MpsPerFpm is meters per second in one foot per minute.
+	MpsPerFpm = MetersPerFoot / 60
+)
+
+func FeetToMeters(ft float64) float64          { return ft * MetersPerFoot }
+func MetersToFeet(m float64) float64           { return m / MetersPerFoot }
+func KnotsToMps(kts float64) float64           { return kts * MpsPerKnot }
+func MpsToKnots(mps float64) float64           { return mps / MpsPerKnot }
+func FpmToMps(fpm float64) float64             { return fpm * MpsPerFpm }
+func MpsToFpm(mps float64) float64             { return mps / MpsPerFpm }
+func MetersToNauticalMiles(m float64) float64  { return m / MetersPerNauticalMile }
+func NauticalMilesToMeters(nm float64) float64 { return nm * MetersPerNauticalMile }
+
+// Round keeps a converted value to a sensible number of decimals.
+func Round(v float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(v*p) / p
+}
+
+// System is the unit system values are rendered in. It implements
+// flag.Value so it can be set from the command line.
+type System string
+
+const (
+	// Aviation uses feet, knots, feet per minute and nautical miles.
+	Aviation System = "aviation"
+	// Metric uses meters, kilometers per hour, meters per second and
+	// kilometers.
+	Metric System = "metric"
+)
+
+func ParseSystem(name string) (System, error) {
+	switch System(strings.ToLower(strings.TrimSpace(name))) {
+	case Aviation, "":
+		return Aviation, nil
+	case Metric, "si":
+		return Metric, nil
+	}
+	return "", fmt.Errorf("unknown unit system %q (want aviation or metric)", name)
+}
+
+func (s *System) String() string {
+	if s == nil || *s == "" {
+		return string(Aviation)
+	}
+	return string(*s)
+}
+
+func (s *System) Set(name string) error {
+	sys, err := ParseSystem(name)
+	if err != nil {
+		return err
+	}
+	*s = sys
+	return nil
+}
+
+// Value is a number together with the unit it is expressed in.
+type Value struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+func (v Value) String() string {
+	return fmt.Sprintf("%g %s", v.Value, v.Unit)
+}
+
+// Altitude renders an altitude given in meters.
+func (s System) Altitude(m float64) Value {
+	if s == Metric {
+		return Value{Round(m, 0), "m"}
+	}
+	return Value{Round(MetersToFeet(m), 0), "ft"}
+}
+
+// Speed renders a speed given in meters per second.
+func (s System) Speed(mps float64) Value {
+	if s == Metric {
+		return Value{Round(mps*3.6, 0), "km/h"}
+	}
+	return Value{Round(MpsToKnots(mps), 0), "kt"}
+}
+
+// VerticalRate renders a vertical rate given in meters per second.
+func (s System) VerticalRate(mps float64) Value {
+	if s == Metric {
+		return Value{Round(mps, 1), "m/s"}
+	}
+	return Value{Round(MpsToFpm(mps), 0), "ft/min"}
+}
+
+// Distance renders a distance given in meters.
+func (s System) Distance(m float64) Value {
+	if s == Metric {
+		return Value{Round(m/1000, 1), "km"}
+	}
+	return Value{Round(MetersToNauticalMiles(m), 1), "nm"}
+}